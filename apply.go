@@ -0,0 +1,167 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+// Apply traverses v recursively in depth-first order,
+// calling pre for each value before its members or elements are traversed
+// and post for each value after they are traversed.
+// Either function may be nil.
+//
+// If pre returns false, the members or elements of the current value
+// are not traversed and post is not called for it.
+// If post returns false, traversal stops and Apply returns false.
+// Otherwise, Apply returns true.
+//
+// The Cursor passed to pre and post may be used to modify the tree.
+// Values that are inserted or replaced using the cursor are not traversed.
+// Comments are moved along with any values that are inserted, replaced,
+// or deleted using the same heuristics as Patch.
+func (v *Value) Apply(pre, post func(*Cursor) bool) bool {
+	a := applier{pre: pre, post: post}
+	return a.apply(&Cursor{root: v})
+}
+
+// Cursor describes a value encountered during Apply.
+// It is only valid for the duration of the call to pre or post.
+type Cursor struct {
+	root   *Value    // only set for the root value
+	parent *Value    // the object or array containing the value
+	iter   *iterator // position within parent
+
+	replaced, deleted bool
+}
+
+type iterator struct {
+	index int // index of the current value within the parent
+	step  int // amount to advance index by to obtain the next value
+}
+
+type applier struct {
+	pre, post func(*Cursor) bool
+}
+
+func (a *applier) apply(c *Cursor) bool {
+	if a.pre != nil && (!a.pre(c) || c.deleted) {
+		return true
+	}
+	if !c.replaced {
+		if comp, ok := c.Value().Value.(composite); ok {
+			var it iterator
+			for it.index = 0; it.index < comp.length(); it.index += it.step {
+				it.step = 1
+				if !a.apply(&Cursor{parent: c.Value(), iter: &it}) {
+					return false
+				}
+			}
+		}
+	}
+	if a.post != nil && !c.deleted && !a.post(c) {
+		return false
+	}
+	return true
+}
+
+func (c *Cursor) comp() composite {
+	return c.parent.Value.(composite)
+}
+
+// Value returns the current value.
+func (c *Cursor) Value() *Value {
+	if c.iter == nil {
+		return c.root
+	}
+	switch parent := c.parent.Value.(type) {
+	case *Object:
+		return &parent.Members[c.iter.index].Value
+	default:
+		return &parent.(*Array).Elements[c.iter.index]
+	}
+}
+
+// Parent returns the object or array containing the current value.
+// It returns nil for the root value.
+func (c *Cursor) Parent() *Value {
+	return c.parent
+}
+
+// Name returns the member name of the current value
+// if the parent is an object, otherwise it returns the empty string.
+func (c *Cursor) Name() string {
+	if c.iter == nil {
+		return ""
+	}
+	if obj, ok := c.parent.Value.(*Object); ok {
+		return obj.Members[c.iter.index].Name.Value.(Literal).memberName()
+	}
+	return ""
+}
+
+// Index returns the index of the current value within its parent.
+// It returns -1 for the root value.
+func (c *Cursor) Index() int {
+	if c.iter == nil {
+		return -1
+	}
+	return c.iter.index
+}
+
+// Replace replaces the current value with v.
+// The comments associated with the current value are replaced by
+// those in v.BeforeExtra and v.AfterExtra, if any.
+func (c *Cursor) Replace(v Value) {
+	if c.iter == nil {
+		c.root.Value = v.Value
+		if v.BeforeExtra.hasComment() {
+			c.root.BeforeExtra = v.BeforeExtra
+		}
+		if v.AfterExtra.hasComment() {
+			c.root.AfterExtra = v.AfterExtra
+		}
+	} else {
+		replaceAt(c.comp(), c.iter.index, v)
+	}
+	c.replaced = true
+}
+
+// Delete deletes the current value along with its associated comments.
+// It panics for the root value.
+// The cursor must not be used after the value is deleted.
+func (c *Cursor) Delete() {
+	if c.iter == nil {
+		panic("hujson: cannot delete root value")
+	}
+	removeAt(c.comp(), c.iter.index)
+	c.iter.step--
+	c.deleted = true
+}
+
+// InsertBefore inserts v before the current value in its parent.
+// The name is used if the parent is an object and is otherwise ignored.
+// It panics for the root value.
+func (c *Cursor) InsertBefore(name string, v Value) {
+	if c.iter == nil {
+		panic("hujson: cannot insert before root value")
+	}
+	c.insertAt(c.iter.index, name, v)
+	c.iter.index++
+}
+
+// InsertAfter inserts v after the current value in its parent.
+// The name is used if the parent is an object and is otherwise ignored.
+// It panics for the root value.
+func (c *Cursor) InsertAfter(name string, v Value) {
+	if c.iter == nil {
+		panic("hujson: cannot insert after root value")
+	}
+	c.insertAt(c.iter.index+1, name, v)
+	c.iter.step++
+}
+
+func (c *Cursor) insertAt(i int, name string, v Value) {
+	insertAt(c.comp(), i, v)
+	if obj, ok := c.parent.Value.(*Object); ok {
+		obj.Members[i].Name.Value = String(name)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyTraversal(t *testing.T) {
+	v, err := Parse([]byte(`{"a": [1, {"b": 2}], "skip": [3], "c": 4, "d": 5}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	var got []string
+	visit := func(kind string, stop string) func(*Cursor) bool {
+		return func(c *Cursor) bool {
+			var parent Kind
+			if c.Parent() != nil {
+				parent = c.Parent().Value.Kind()
+			}
+			got = append(got, fmt.Sprintf("%s %q %d %q %s", kind, c.Name(), c.Index(), parent, Value{Value: c.Value().Value}.Pack()))
+			return c.Name() != stop
+		}
+	}
+	completed := v.Apply(visit("pre", "skip"), visit("post", "c"))
+	want := []string{
+		`pre "" -1 '\x00' {"a": [1, {"b": 2}], "skip": [3], "c": 4, "d": 5}`,
+		`pre "a" 0 '{' [1, {"b": 2}]`,
+		`pre "" 0 '[' 1`,
+		`post "" 0 '[' 1`,
+		`pre "" 1 '[' {"b": 2}`,
+		`pre "b" 0 '{' 2`,
+		`post "b" 0 '{' 2`,
+		`post "" 1 '[' {"b": 2}`,
+		`post "a" 0 '{' [1, {"b": 2}]`,
+		`pre "skip" 1 '{' [3]`,
+		`pre "c" 2 '{' 4`,
+		`post "c" 2 '{' 4`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Apply traversal mismatch (-want +got):\n%s", diff)
+	}
+	if completed {
+		t.Errorf("Apply = true, want false")
+	}
+}
+
+func TestApplyRewrite(t *testing.T) {
+	v, err := Parse([]byte(`{
+	// Comment for x1.
+	"x1": 1,
+	"keep": [1, 2, 3, 4, 5], // Comment for keep.
+	// Comment for x2.
+	"x2": {"x3": 3},
+}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	completed := v.Apply(func(c *Cursor) bool {
+		switch {
+		case c.Name() == "x1":
+			c.InsertBefore("before", Value{Value: String("inserted"), BeforeExtra: Extra("\n// Inserted before.\n")})
+			c.Replace(Value{Value: Int(100)})
+		case c.Name() == "keep":
+			c.InsertAfter("after", Value{Value: String("inserted")})
+		case c.Name() == "x2":
+			c.Delete()
+		case c.Name() == "after":
+			t.Errorf("Apply traversed inserted value")
+		case c.Parent() != nil && c.Parent().Value.Kind() == '[' && c.Value().Value.(Literal).Int()%2 == 0:
+			c.Delete()
+		}
+		return true
+	}, func(c *Cursor) bool {
+		if c.Parent() != nil && c.Parent().Value.Kind() == '[' {
+			c.Replace(Value{Value: Int(c.Value().Value.(Literal).Int() * 10)})
+		}
+		return true
+	})
+	if !completed {
+		t.Errorf("Apply = false, want true")
+	}
+	v.Format()
+	got := v.String()
+	want, err := Format([]byte(`{
+	// Inserted before.
+	"before": "inserted",
+	// Comment for x1.
+	"x1": 100,
+	"keep": [10, 30, 50], // Comment for keep.
+	"after": "inserted",
+}`))
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+	if diff := cmp.Diff(string(want), got); diff != "" {
+		t.Errorf("Apply mismatch (-want +got):\n%s\n\ngot:\n%s", diff, got)
+	}
+}
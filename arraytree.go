@@ -0,0 +1,203 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+// minTreeElements is the minimum number of elements that an array must have
+// before findCache represents it as an arrayTree when it is resized.
+// Smaller arrays are faster to resize by copying.
+const minTreeElements = 256
+
+// arrayTree is an order statistic tree over the elements of an Array,
+// which provides O(log n) insertion, removal, and indexing
+// (see https://en.wikipedia.org/wiki/Order_statistic_tree).
+//
+// While a tree exists for an array, it is the authoritative representation
+// of the elements and Array.Elements is stale. Call flush to store
+// the elements back into the array.
+//
+// The tree is implemented as a treap keyed implicitly by the element index,
+// where the randomly chosen priorities keep the tree balanced in expectation.
+type arrayTree struct {
+	arr  *Array
+	root *arrayNode
+	seed uint64 // state for generating priorities
+}
+
+type arrayNode struct {
+	value       Value
+	left, right *arrayNode
+	size        int // number of nodes in the subtree rooted at this node
+	priority    uint64
+}
+
+func newArrayTree(arr *Array) *arrayTree {
+	t := &arrayTree{arr: arr, seed: 0x9e3779b97f4a7c15}
+
+	// Build a treap from a sorted sequence in O(n) by maintaining
+	// the right spine of the tree on a stack.
+	var stack []*arrayNode
+	for i := range arr.Elements {
+		n := &arrayNode{value: arr.Elements[i], priority: t.nextPriority()}
+		var last *arrayNode
+		for len(stack) > 0 && stack[len(stack)-1].priority < n.priority {
+			last = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		}
+		n.left = last
+		if len(stack) > 0 {
+			stack[len(stack)-1].right = n
+		}
+		stack = append(stack, n)
+	}
+	if len(stack) > 0 {
+		t.root = stack[0]
+		t.root.updateSizes()
+	}
+	return t
+}
+
+// nextPriority returns a pseudo-random priority using xorshift64*.
+func (t *arrayTree) nextPriority() uint64 {
+	t.seed ^= t.seed >> 12
+	t.seed ^= t.seed << 25
+	t.seed ^= t.seed >> 27
+	return t.seed * 0x2545f4914f6cdd1d
+}
+
+// flush stores the elements in the tree back into the array.
+func (t *arrayTree) flush() {
+	elems := make([]ArrayElement, 0, t.length())
+	t.rangeValues(func(v *Value) bool {
+		elems = append(elems, *v)
+		return true
+	})
+	if len(elems) == 0 && t.arr.Elements == nil {
+		elems = nil
+	}
+	t.arr.Elements = elems
+}
+
+func (n *arrayNode) updateSizes() int {
+	if n == nil {
+		return 0
+	}
+	n.size = 1 + n.left.updateSizes() + n.right.updateSizes()
+	return n.size
+}
+
+func (n *arrayNode) getSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// split splits the subtree at n such that the first k nodes are in l
+// and the remaining nodes are in r.
+func (n *arrayNode) split(k int) (l, r *arrayNode) {
+	if n == nil {
+		return nil, nil
+	}
+	if k <= n.left.getSize() {
+		l, n.left = n.left.split(k)
+		n.size = 1 + n.left.getSize() + n.right.getSize()
+		return l, n
+	}
+	n.right, r = n.right.split(k - n.left.getSize() - 1)
+	n.size = 1 + n.left.getSize() + n.right.getSize()
+	return n, r
+}
+
+// mergeNodes concatenates the subtrees at l and r.
+func mergeNodes(l, r *arrayNode) *arrayNode {
+	switch {
+	case l == nil:
+		return r
+	case r == nil:
+		return l
+	case l.priority > r.priority:
+		l.right = mergeNodes(l.right, r)
+		l.size = 1 + l.left.getSize() + l.right.getSize()
+		return l
+	default:
+		r.left = mergeNodes(l, r.left)
+		r.size = 1 + r.left.getSize() + r.right.getSize()
+		return r
+	}
+}
+
+// nodeAt returns the i-th node, which must exist.
+func (t *arrayTree) nodeAt(i int) *arrayNode {
+	n := t.root
+	for {
+		switch k := n.left.getSize(); {
+		case i < k:
+			n = n.left
+		case i > k:
+			n, i = n.right, i-k-1
+		default:
+			return n
+		}
+	}
+}
+
+func (t *arrayTree) valueAt(i int) *Value {
+	return &t.nodeAt(i).value
+}
+
+func (t *arrayTree) length() int {
+	return t.root.getSize()
+}
+func (t *arrayTree) firstValue() *Value {
+	if t.length() > 0 {
+		return t.valueAt(0)
+	}
+	return nil
+}
+func (t *arrayTree) rangeValues(f func(*Value) bool) bool {
+	var walk func(n *arrayNode) bool
+	walk = func(n *arrayNode) bool {
+		return n == nil || (walk(n.left) && f(&n.value) && walk(n.right))
+	}
+	return walk(t.root)
+}
+func (t *arrayTree) lastValue() *Value {
+	if t.length() > 0 {
+		return t.valueAt(t.length() - 1)
+	}
+	return nil
+}
+
+func (t *arrayTree) getAt(i int) ValueTrimmed {
+	return t.valueAt(i).Value
+}
+func (t *arrayTree) setAt(i int, v ValueTrimmed) {
+	t.valueAt(i).Value = v
+}
+func (t *arrayTree) insertAt(i int, v ValueTrimmed) {
+	n := &arrayNode{value: ArrayElement{Value: v}, size: 1, priority: t.nextPriority()}
+	l, r := t.root.split(i)
+	t.root = mergeNodes(mergeNodes(l, n), r)
+}
+func (t *arrayTree) removeAt(i int) ValueTrimmed {
+	l, r := t.root.split(i)
+	n, r := r.split(1)
+	t.root = mergeNodes(l, r)
+	return n.value.Value
+}
+
+func (t *arrayTree) beforeExtraAt(i int) *Extra {
+	if i < t.length() {
+		return &t.valueAt(i).BeforeExtra
+	}
+	return &t.arr.AfterExtra
+}
+func (t *arrayTree) afterExtra() *Extra {
+	return &t.arr.AfterExtra
+}
+
+func (arrayTree) Kind() Kind { return '[' }
+
+var _ composite = (*arrayTree)(nil)
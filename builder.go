@@ -0,0 +1,168 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Builder constructs a JSON object or array with comments.
+// Methods may be chained, where the first error encountered
+// is reported by Build and all subsequent calls are ignored.
+//
+// Example usage:
+//
+//	v, err := hujson.NewObject().
+//		Comment("The name of the service.").
+//		Field("name", hujson.String("x")).
+//		Field("ports", hujson.NewArray().Element(80).Element(443)).
+//		Build()
+//	if err != nil {
+//		... // handle err
+//	}
+//	v.Format()
+type Builder struct {
+	comp    composite
+	pending Extra // comments for the next member or element
+	err     error
+}
+
+// NewObject returns a Builder for a JSON object.
+// Each member is placed on its own line.
+func NewObject() *Builder {
+	return &Builder{comp: new(Object)}
+}
+
+// NewArray returns a Builder for a JSON array.
+// Elements are placed on a single line unless any have comments.
+func NewArray() *Builder {
+	return &Builder{comp: new(Array)}
+}
+
+// Comment adds a line comment before the next member or element,
+// or before the closing brace or bracket if there are no more.
+// Text with multiple lines produces a line comment for each line.
+func (b *Builder) Comment(text string) *Builder {
+	if b.checkComment(text) {
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimRight(line, "\r")
+			if line != "" {
+				line = " " + line
+			}
+			b.pending = append(b.pending, "\n//"+line...)
+		}
+	}
+	return b
+}
+
+// BlockComment adds a block comment before the next member or element,
+// or before the closing brace or bracket if there are no more.
+// Any occurrence of "*/" in text is escaped as "* /".
+func (b *Builder) BlockComment(text string) *Builder {
+	if b.checkComment(text) {
+		text = strings.ReplaceAll(text, "*/", "* /")
+		b.pending = append(b.pending, "\n/* "+text+" */"...)
+	}
+	return b
+}
+
+func (b *Builder) checkComment(text string) bool {
+	if b.err == nil && !utf8.ValidString(text) {
+		b.err = fmt.Errorf("hujson: invalid UTF-8 in comment %q", text)
+	}
+	return b.err == nil
+}
+
+// Field adds an object member with the given name and value,
+// which is either a *Builder, Value, *Value, ValueTrimmed,
+// or any Go value that can be marshaled by the "encoding/json" package.
+// It reports an error if b is building an array.
+func (b *Builder) Field(name string, v interface{}) *Builder {
+	obj, ok := b.comp.(*Object)
+	if b.err == nil && !ok {
+		b.err = fmt.Errorf("hujson: cannot add member %q to array", name)
+	}
+	if b.err != nil {
+		return b
+	}
+	val, ok := b.value(v)
+	if !ok {
+		return b
+	}
+	obj.Members = append(obj.Members, ObjectMember{
+		Name:  Value{BeforeExtra: append(b.pending, '\n'), Value: String(name)},
+		Value: val,
+	})
+	b.pending = nil
+	return b
+}
+
+// Element adds an array element with the given value,
+// which is either a *Builder, Value, *Value, ValueTrimmed,
+// or any Go value that can be marshaled by the "encoding/json" package.
+// It reports an error if b is building an object.
+func (b *Builder) Element(v interface{}) *Builder {
+	arr, ok := b.comp.(*Array)
+	if b.err == nil && !ok {
+		b.err = fmt.Errorf("hujson: cannot add element to object")
+	}
+	if b.err != nil {
+		return b
+	}
+	val, ok := b.value(v)
+	if !ok {
+		return b
+	}
+	if b.pending != nil {
+		val.BeforeExtra = append(b.pending, '\n')
+	}
+	arr.Elements = append(arr.Elements, val)
+	b.pending = nil
+	return b
+}
+
+// value converts v to a Value, recording any error in b.
+func (b *Builder) value(v interface{}) (val Value, ok bool) {
+	var err error
+	if b2, isBuilder := v.(*Builder); isBuilder {
+		val, err = b2.Build()
+	} else if val, err = toValue(v); err == nil {
+		switch {
+		case val.Value == nil:
+			err = fmt.Errorf("hujson: missing value")
+		case !val.BeforeExtra.IsValid() || !val.AfterExtra.IsValid():
+			err = fmt.Errorf("hujson: invalid whitespace or comments around value")
+		case val.Value.Kind() != '{' && val.Value.Kind() != '[' && !val.Value.(Literal).IsValid():
+			err = fmt.Errorf("hujson: invalid literal %s", val.Value.(Literal))
+		}
+	}
+	if err != nil {
+		b.err = err
+		return Value{}, false
+	}
+	return val, true
+}
+
+// Build returns the constructed value or the first error encountered.
+// The Builder must not be used afterwards.
+// It does not format the value. It is recommended that Format be called after.
+func (b *Builder) Build() (Value, error) {
+	if b.err != nil {
+		return Value{}, b.err
+	}
+	multiline := b.pending != nil
+	b.comp.rangeValues(func(v *Value) bool {
+		multiline = multiline || v.BeforeExtra.hasNewline()
+		return !multiline
+	})
+	if multiline {
+		*b.comp.afterExtra() = append(b.pending, '\n')
+	}
+	v := Value{Value: b.comp.(ValueTrimmed)}
+	v.UpdateOffsets()
+	return v, nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuilder(t *testing.T) {
+	v, err := NewObject().
+		Comment("The name.\nSecond line.").
+		Field("name", String("x")).
+		Field("ports", NewArray().Element(80).Element(443)).
+		Field("nested", NewArray().BlockComment("a */ b").Element(1).Element(map[string]int{"a": 1})).
+		Field("empty", NewObject()).
+		Comment("Trailing comment.").
+		Build()
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	v.Format()
+	got := v.String()
+	want := `{
+	// The name.
+	// Second line.
+	"name":  "x",
+	"ports": [80, 443],
+	"nested": [
+		/* a * / b */
+		1,
+		{"a": 1},
+	],
+	"empty": {},
+	// Trailing comment.
+}
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Build mismatch (-want +got):\n%s\n\ngot:\n%s", diff, got)
+	}
+	if _, err := Parse([]byte(got)); err != nil {
+		t.Errorf("Parse error: %v", err)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *Builder
+	}{
+		{"FieldInArray", NewArray().Field("a", 1)},
+		{"ElementInObject", NewObject().Element(1)},
+		{"InvalidUTF8", NewObject().Comment("\xff")},
+		{"InvalidLiteral", NewArray().Element(Literal("nope"))},
+		{"InvalidExtra", NewArray().Element(Value{BeforeExtra: Extra("x"), Value: Int(1)})},
+		{"Unmarshalable", NewArray().Element(make(chan int))},
+		{"NestedError", NewObject().Field("a", NewArray().Field("b", 1))},
+		{"ErrorIsSticky", NewArray().Field("a", 1).Element(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.b.Build(); err == nil {
+				t.Errorf("Build succeeded, want error")
+			}
+		})
+	}
+}
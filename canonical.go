@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Canonical canonicalizes b according to the
+// JSON Canonicalization Scheme (JCS) per RFC 8785.
+// If an error is encountered, then b is returned as is along with the error.
+func Canonical(b []byte) ([]byte, error) {
+	ast, err := Parse(b)
+	if err != nil {
+		return b, err
+	}
+	if err := ast.Canonicalize(); err != nil {
+		return b, err
+	}
+	return ast.Pack(), nil
+}
+
+// Canonicalize converts v into its canonical form according to the
+// JSON Canonicalization Scheme (JCS) per RFC 8785, which is suitable
+// for hashing or signing such that the result does not depend on
+// comments, whitespace, trailing commas, or the order of object members.
+//
+// All comments, whitespace, and trailing commas are removed,
+// unquoted keys are quoted, object members are sorted by name
+// according to their UTF-16 code units, strings are escaped minimally,
+// and numbers are formatted as in ECMAScript (e.g., 1E3 as 1000).
+//
+// It reports an error if an object has duplicate member names,
+// if a string is invalid, or if a number cannot be represented as
+// an IEEE 754 double-precision number, in which case v is unmodified.
+func (v *Value) Canonicalize() error {
+	v2 := v.Clone()
+	v2.Standardize()
+	v2.Minimize()
+	if err := v2.canonicalize(); err != nil {
+		return err
+	}
+	*v = v2
+	v.UpdateOffsets()
+	return nil
+}
+
+func (v *Value) canonicalize() error {
+	switch v2 := v.Value.(type) {
+	case Literal:
+		switch v2.Kind() {
+		case '"':
+			s, err := v2.Str()
+			if err != nil {
+				return err
+			}
+			if !utf8.Valid(v2) {
+				return fmt.Errorf("hujson: invalid UTF-8 in string %s", v2)
+			}
+			v.Value = String(s)
+		case '0':
+			f, err := v2.Float64()
+			if err != nil {
+				return err
+			}
+			if f == 0 {
+				f = 0 // canonicalize negative zero
+			}
+			v.Value = Float(f)
+		}
+	case *Object:
+		names := make(map[string]bool, len(v2.Members))
+		for i := range v2.Members {
+			name := &v2.Members[i].Name
+			if err := name.canonicalize(); err != nil {
+				return err
+			}
+			s := name.Value.(Literal).String()
+			if names[s] {
+				return fmt.Errorf("hujson: duplicate member name %q", s)
+			}
+			names[s] = true
+			if err := v2.Members[i].Value.canonicalize(); err != nil {
+				return err
+			}
+		}
+		sort.Slice(v2.Members, func(i, j int) bool {
+			return lessUTF16(v2.Members[i].Name.Value.(Literal).String(), v2.Members[j].Name.Value.(Literal).String())
+		})
+	case *Array:
+		for i := range v2.Elements {
+			if err := v2.Elements[i].canonicalize(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lessUTF16 reports whether x sorts before y when compared
+// as sequences of UTF-16 code units.
+func lessUTF16(x, y string) bool {
+	ux, uy := utf16.Encode([]rune(x)), utf16.Encode([]rune(y))
+	for i := 0; i < len(ux) && i < len(uy); i++ {
+		if ux[i] != uy[i] {
+			return ux[i] < uy[i]
+		}
+	}
+	return len(ux) < len(uy)
+}
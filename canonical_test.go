@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{{
+		// RFC 8785, section 3.2.2.
+		in: `{
+			"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+			"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+			"literals": [null, true, false]
+		}`,
+		want: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+	}, {
+		// RFC 8785, section 3.2.3.
+		in: `{
+			"\u20ac": "Euro Sign",
+			"\r": "Carriage Return",
+			"\ufb33": "Hebrew Letter Dalet With Dagesh",
+			"1": "One",
+			"\ud83d\ude00": "Emoji: Grinning Face",
+			"\u0080": "Control",
+			"\u00f6": "Latin Small Letter O With Diaeresis"
+		}`,
+		want: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\",\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+	}, {
+		in: `// Comment.
+		{
+			b: [1e0, -0, 100000000000000000000000, 1e-7,], // Trailing comment.
+			a: {z: 1, y: 2},
+		}`,
+		want: `{"a":{"y":2,"z":1},"b":[1,0,1e+23,1e-7]}`,
+	}, {
+		in:      `{"a": 1, a: 2}`,
+		wantErr: true,
+	}, {
+		in:      `[1e400]`,
+		wantErr: true,
+	}, {
+		in:      "[\"\xff\"]",
+		wantErr: true,
+	}, {
+		in:      `["\ud800"]`,
+		wantErr: true,
+	}, {
+		in:      `["\udc00\ud800"]`,
+		wantErr: true,
+	}, {
+		in:      `["\ud83d\u0041"]`,
+		wantErr: true,
+	}, {
+		in:   `["\ud83d\uDE00", "\\ud800", "\ufffd"]`,
+		want: `["😀","\\ud800","�"]`,
+	}}
+	for _, tt := range tests {
+		got, err := Canonical([]byte(tt.in))
+		switch {
+		case tt.wantErr && err == nil:
+			t.Errorf("Canonical(%q) succeeded, want error", tt.in)
+		case !tt.wantErr && err != nil:
+			t.Errorf("Canonical(%q) error: %v", tt.in, err)
+		case !tt.wantErr && string(got) != tt.want:
+			t.Errorf("Canonical(%q):\ngot  %s\nwant %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/nrawrx3/hujson"
+)
+
+// rewriteComments applies the -strip-comments, -normalize-comments,
+// and -wrap-comments policies to the comments in src.
+func rewriteComments(src []byte) ([]byte, error) {
+	v, err := hujson.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	err = v.RewriteComments(func(c hujson.Comment) (string, bool) {
+		if stripPattern != nil && stripPattern.MatchString(c.Text) {
+			return "", false
+		}
+		if c.Style == hujson.LineComment && *wrapComments > 0 && len(c.Text) > *wrapComments {
+			return wrapLineComment(c.Text, *wrapComments), true
+		}
+		raw := rawComment(src, c.Offset)
+		if *normalizeComments && !strings.HasPrefix(raw, "/*!") {
+			return c.String(), true // banners are kept as is
+		}
+		return raw, true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.Pack(), nil
+}
+
+// rawComment returns the comment that starts at offset in src as is.
+func rawComment(src []byte, offset int) string {
+	s := string(src[offset:])
+	if strings.HasPrefix(s, "//") {
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			return s[:i]
+		}
+		return s
+	}
+	if i := strings.Index(s, "*/"); i >= 0 {
+		return s[:i+len("*/")]
+	}
+	return s
+}
+
+// wrapLineComment formats text as line comments,
+// breaking lines between words so that no line exceeds width
+// unless it consists of a single word.
+func wrapLineComment(text string, width int) string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+len(" ")+len(word) > width {
+			lines = append(lines, "// "+line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	lines = append(lines, "// "+line)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nrawrx3/hujson"
+	"github.com/nrawrx3/hujson/doc"
+)
+
+// docMain implements "hujsonfmt doc [-format markdown|json] [path]",
+// which prints reference documentation generated from the comments
+// in a sample HuJSON document read from path or standard input.
+func docMain(args []string) error {
+	fs := flag.NewFlagSet("doc", flag.ContinueOnError)
+	format := fs.String("format", "markdown", "output format: markdown or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var src []byte
+	var err error
+	switch fs.NArg() {
+	case 0:
+		src, err = readFile("<standard input>", os.Stdin)
+	case 1:
+		src, err = readFile(fs.Arg(0), nil)
+	default:
+		return fmt.Errorf("doc accepts at most one path")
+	}
+	if err != nil {
+		return err
+	}
+	v, err := hujson.Parse(src)
+	if err != nil {
+		return err
+	}
+
+	entries := doc.Extract(v)
+	switch *format {
+	case "markdown":
+		return doc.WriteMarkdown(os.Stdout, entries)
+	case "json":
+		return doc.WriteJSON(os.Stdout, entries)
+	default:
+		return fmt.Errorf("unknown doc format %q", *format)
+	}
+}
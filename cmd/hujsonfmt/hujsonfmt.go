@@ -1,12 +1,15 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 
@@ -26,6 +29,37 @@ var (
 	write = flag.Bool("w", false,
 		"write result to (source) file instead of stdout",
 	)
+	keepBanners = flag.Bool("keep-banners", false,
+		"keep /*! ... */ comments when minifying or standardizing",
+	)
+	keepComments = flag.String("keep-comments", "",
+		"keep comments matching the regular expression when minifying or standardizing",
+	)
+	commentsOut = flag.String("comments-out", "",
+		"write kept comments that cannot be represented in standard JSON to this file as JSON lines",
+	)
+	stripComments = flag.String("strip-comments", "",
+		"remove comments matching the regular expression (e.g., TODO)",
+	)
+	normalizeComments = flag.Bool("normalize-comments", false,
+		"normalize the spacing within comments (e.g., //comment to // comment), except for /*! banners",
+	)
+	wrapComments = flag.Int("wrap-comments", 0,
+		"wrap line comments with text longer than this many characters",
+	)
+	canonicalNumbers = flag.Bool("canonical-numbers", false,
+		"format numbers canonically (e.g., 1.0e+02 as 100) when exactly representable",
+	)
+	lowercaseExponents = flag.Bool("lowercase-exponents", false,
+		"format number exponents with a lowercase e",
+	)
+	escapeInvisible = flag.Bool("escape-invisible", false,
+		"escape invisible and bidirectional control characters in strings",
+	)
+
+	keepPattern  *regexp.Regexp
+	stripPattern *regexp.Regexp
+	commentsFile *os.File
 
 	chmodSupported = runtime.GOOS != "windows"
 	huJSONExt      = ".hujson"
@@ -33,11 +67,16 @@ var (
 
 func usage() {
 	fmt.Fprintf(os.Stderr, "usage: hujsonfmt [flags] [path ...]\n")
+	fmt.Fprintf(os.Stderr, "       hujsonfmt merge BASE OURS THEIRS\n")
+	fmt.Fprintf(os.Stderr, "       hujsonfmt doc [-format markdown|json] [path]\n")
 	flag.PrintDefaults()
 }
 
 func main() {
 	err := mainE()
+	if errors.Is(err, errConflicts) {
+		os.Exit(1)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err)
 		usage()
@@ -51,6 +90,34 @@ func mainE() error {
 
 	args := flag.Args()
 
+	if *keepComments != "" {
+		var err error
+		if keepPattern, err = regexp.Compile(*keepComments); err != nil {
+			return fmt.Errorf("invalid -keep-comments pattern: %w", err)
+		}
+	}
+	if *stripComments != "" {
+		var err error
+		if stripPattern, err = regexp.Compile(*stripComments); err != nil {
+			return fmt.Errorf("invalid -strip-comments pattern: %w", err)
+		}
+	}
+
+	if len(args) > 0 && args[0] == "merge" {
+		return mergeMain(args[1:])
+	}
+	if len(args) > 0 && args[0] == "doc" {
+		return docMain(args[1:])
+	}
+
+	if *commentsOut != "" {
+		var err error
+		if commentsFile, err = os.Create(*commentsOut); err != nil {
+			return err
+		}
+		defer commentsFile.Close()
+	}
+
 	if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
 		stat, _ := os.Stdin.Stat()
 		if (stat.Mode() & os.ModeCharDevice) != 0 {
@@ -109,7 +176,7 @@ func processFile(info fs.FileInfo, filename string, in io.Reader) error {
 	input := make([]byte, len(src))
 	_ = copy(input, src)
 
-	output, err := processSrc(input)
+	output, err := processSrc(filename, input)
 	if err != nil {
 		return err
 	}
@@ -149,16 +216,27 @@ func readFile(path string, in io.Reader) ([]byte, error) {
 	return src, nil
 }
 
-func processSrc(src []byte) ([]byte, error) {
+func processSrc(filename string, src []byte) ([]byte, error) {
 	var r []byte
 	var err error
+	if stripPattern != nil || *normalizeComments || *wrapComments > 0 {
+		if src, err = rewriteComments(src); err != nil {
+			return nil, err
+		}
+	}
 	switch {
+	case (*min || *stand) && (*keepBanners || keepPattern != nil):
+		r, err = processKeptComments(filename, src)
 	case *min:
 		r, err = hujson.Minimize(src)
 	case *stand:
 		r, err = hujson.Standardize(src)
 	default:
-		r, err = hujson.Format(src)
+		r, err = hujson.FormatWithOptions(src, hujson.FormatOptions{
+			CanonicalNumbers:   *canonicalNumbers,
+			LowercaseExponents: *lowercaseExponents,
+			EscapeInvisible:    *escapeInvisible,
+		})
 	}
 	if err != nil {
 		return nil, err
@@ -167,6 +245,50 @@ func processSrc(src []byte) ([]byte, error) {
 	return r, nil
 }
 
+// processKeptComments minifies or standardizes src while keeping the
+// comments selected by -keep-banners and -keep-comments.
+// Kept comments that cannot remain in standard JSON output
+// are written to the -comments-out file.
+func processKeptComments(filename string, src []byte) ([]byte, error) {
+	v, err := hujson.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []hujson.Comment
+	if *min {
+		removed = v.MinimizeWithOptions(hujson.MinimizeOptions{
+			KeepBanners: *keepBanners,
+			Keep:        keepPattern,
+			Standard:    *stand,
+		})
+	} else {
+		removed = v.StandardizeWithOptions(hujson.StandardizeOptions{
+			KeepBanners: *keepBanners,
+			Keep:        keepPattern,
+		})
+	}
+
+	if len(removed) > 0 {
+		if commentsFile == nil {
+			return nil, fmt.Errorf("%s: kept comments cannot be represented in standard JSON; use -comments-out", filename)
+		}
+		enc := json.NewEncoder(commentsFile)
+		for _, c := range removed {
+			err := enc.Encode(struct {
+				File    string `json:"file"`
+				Offset  int    `json:"offset"`
+				Comment string `json:"comment"`
+			}{filename, c.Offset, c.String()})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return v.Pack(), nil
+}
+
 func printDiff(filename string, src, modified []byte) {
 	origFile := filename + ".orig"
 	old := string(src)
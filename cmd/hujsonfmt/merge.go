@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nrawrx3/hujson"
+)
+
+// errConflicts reports that a merge completed with conflicts,
+// which git expects to be signaled with a non-zero exit status.
+var errConflicts = errors.New("merge conflicts")
+
+const conflictMarkerSize = 7
+
+// mergeMain implements "hujsonfmt merge BASE OURS THEIRS",
+// which is suitable as a git merge driver invoked as "hujsonfmt merge %O %A %B".
+// The merged result is formatted and written to OURS.
+func mergeMain(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("merge requires exactly three paths: BASE OURS THEIRS")
+	}
+	var vals [3]hujson.Value
+	for i, path := range args {
+		src, err := readFile(path, nil)
+		if err != nil {
+			return err
+		}
+		vals[i], err = hujson.Parse(src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	info, err := os.Stat(args[1])
+	if err != nil {
+		return err
+	}
+
+	out, conflicted := merge3(vals[0], vals[1], vals[2])
+	if err := os.WriteFile(args[1], out, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if conflicted {
+		return errConflicts
+	}
+	return nil
+}
+
+// merge3 merges the changes from base to ours and from base to theirs
+// and formats the result, marking any conflicts with conflict markers.
+func merge3(base, ours, theirs hujson.Value) (out []byte, conflicted bool) {
+	merged, conflicts := hujson.Merge3(base, ours, theirs)
+	merged.Format()
+	out = merged.Pack()
+	if len(conflicts) > 0 {
+		out = markConflicts(out, &merged, conflicts)
+	}
+	return out, len(conflicts) > 0
+}
+
+// edit replaces src[start:end] with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// conflictRegion is a range of whole lines in the merged output
+// that contains one or more conflicts.
+type conflictRegion struct {
+	start, end int
+	ours       []edit // edits to derive our version of the region
+	theirs     []edit // edits to derive their version of the region
+}
+
+// markConflicts surrounds the lines of each conflicting value in src
+// with conflict markers, where the first section holds our version
+// and the second section holds their version.
+// The merged value v must be formatted such that its offsets match src.
+func markConflicts(src []byte, v *hujson.Value, conflicts []hujson.Conflict) []byte {
+	var regions []conflictRegion
+	for _, c := range conflicts {
+		start, end, ok := memberSpan(v, c.Pointer)
+		if !ok {
+			continue
+		}
+		node := v.Find(c.Pointer)
+		r := conflictRegion{start: lineStart(src, start), end: lineEnd(src, end)}
+		switch {
+		case c.Ours == nil:
+			// Merged value is from theirs and absent in ours.
+			r.ours = []edit{deletion(src, start, end)}
+		case c.Theirs == nil:
+			r.theirs = []edit{deletion(src, start, end)}
+		default:
+			indent := src[r.start:start]
+			indent = indent[:len(indent)-len(bytes.TrimLeft(indent, " \t"))]
+			r.theirs = []edit{{node.StartOffset, node.EndOffset, formatValue(*c.Theirs, string(indent))}}
+		}
+		regions = append(regions, r)
+	}
+
+	// Combine overlapping or adjacent regions.
+	sort.Slice(regions, func(i, j int) bool { return regions[i].start < regions[j].start })
+	var combined []conflictRegion
+	for _, r := range regions {
+		if n := len(combined); n > 0 && r.start <= combined[n-1].end {
+			last := &combined[n-1]
+			if r.end > last.end {
+				last.end = r.end
+			}
+			last.ours = append(last.ours, r.ours...)
+			last.theirs = append(last.theirs, r.theirs...)
+			continue
+		}
+		combined = append(combined, r)
+	}
+
+	var out []byte
+	var last int
+	for _, r := range combined {
+		out = append(out, src[last:r.start]...)
+		out = append(out, strings.Repeat("<", conflictMarkerSize)+" ours\n"...)
+		out = append(out, applyEdits(src, r.start, r.end, r.ours)...)
+		out = append(out, strings.Repeat("=", conflictMarkerSize)+"\n"...)
+		out = append(out, applyEdits(src, r.start, r.end, r.theirs)...)
+		out = append(out, strings.Repeat(">", conflictMarkerSize)+" theirs\n"...)
+		last = r.end
+	}
+	return append(out, src[last:]...)
+}
+
+// memberSpan returns the span of the value at ptr,
+// including the member name if the value is an object member.
+func memberSpan(v *hujson.Value, ptr string) (start, end int, ok bool) {
+	node := v.Find(ptr)
+	if node == nil {
+		return 0, 0, false
+	}
+	start, end = node.StartOffset, node.EndOffset
+	if i := strings.LastIndexByte(ptr, '/'); i >= 0 {
+		if parent := v.Find(ptr[:i]); parent != nil {
+			if obj, ok := parent.Value.(*hujson.Object); ok {
+				for j := range obj.Members {
+					if &obj.Members[j].Value == node {
+						start = obj.Members[j].Name.StartOffset
+					}
+				}
+			}
+		}
+	}
+	return start, end, true
+}
+
+// deletion returns an edit that deletes src[start:end] along with any
+// subsequent comma, and the entire line if nothing else is on it.
+func deletion(src []byte, start, end int) edit {
+	if rest := bytes.TrimLeft(src[end:], " \t"); len(rest) > 0 && rest[0] == ',' {
+		end = len(src) - len(rest) + len(",")
+	}
+	ls, le := lineStart(src, start), lineEnd(src, end)
+	if len(bytes.TrimSpace(src[ls:start])) == 0 && len(bytes.TrimSpace(src[end:le])) == 0 {
+		start, end = ls, le
+	} else {
+		end = le - len(bytes.TrimLeft(src[end:le], " \t")) // avoid doubled spaces
+	}
+	return edit{start, end, ""}
+}
+
+// applyEdits returns src[start:end] with the non-overlapping edits applied.
+func applyEdits(src []byte, start, end int, edits []edit) []byte {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var out []byte
+	last := start
+	for _, e := range edits {
+		if e.start < last {
+			continue // overlaps a prior edit
+		}
+		out = append(out, src[last:e.start]...)
+		out = append(out, e.text...)
+		last = e.end
+	}
+	out = append(out, src[last:end]...)
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	return out
+}
+
+// formatValue formats v without its surrounding comments,
+// indenting all lines after the first with indent.
+func formatValue(v hujson.Value, indent string) string {
+	v = v.Clone()
+	v.BeforeExtra, v.AfterExtra = nil, nil
+	v.Format()
+	s := strings.TrimSpace(v.String())
+	return strings.ReplaceAll(s, "\n", "\n"+indent)
+}
+
+func lineStart(src []byte, i int) int {
+	return bytes.LastIndexByte(src[:i], '\n') + 1
+}
+
+func lineEnd(src []byte, i int) int {
+	if j := bytes.IndexByte(src[i:], '\n'); j >= 0 {
+		return i + j + 1
+	}
+	return len(src)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nrawrx3/hujson"
+)
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		wantConflicted     bool
+	}{{
+		name:   "Clean",
+		base:   "{\n\t\"a\": 1,\n\t\"b\": 2,\n}\n",
+		ours:   "{\n\t\"a\": 10,\n\t\"b\": 2,\n}\n",
+		theirs: "{\n\t\"a\": 1,\n\t\"b\": 20,\n}\n",
+		want:   "{\n\t\"a\": 10,\n\t\"b\": 20,\n}\n",
+	}, {
+		name:   "ModifiedOursDeletedTheirs",
+		base:   "{\n\t\"a\": 1,\n\t\"b\": 2,\n\t\"c\": 3,\n}\n",
+		ours:   "{\n\t\"a\": 1,\n\t\"b\": 20,\n\t\"c\": 3,\n}\n",
+		theirs: "{\n\t\"a\": 1,\n\t\"c\": 3,\n}\n",
+		want: `{
+	"a": 1,
+<<<<<<< ours
+	"b": 20,
+=======
+>>>>>>> theirs
+	"c": 3,
+}
+`,
+		wantConflicted: true,
+	}, {
+		name:   "DeletedOursModifiedTheirs",
+		base:   "{\n\t\"a\": 1,\n\t\"b\": 2,\n\t\"c\": 3,\n}\n",
+		ours:   "{\n\t\"a\": 1,\n\t\"c\": 3,\n}\n",
+		theirs: "{\n\t\"a\": 1,\n\t\"b\": 20,\n\t\"c\": 3,\n}\n",
+		want: `{
+	"a": 1,
+<<<<<<< ours
+=======
+	"b": 20,
+>>>>>>> theirs
+	"c": 3,
+}
+`,
+		wantConflicted: true,
+	}, {
+		name:   "SingleLineDeletion",
+		base:   `{"a": 1, "b": 2, "c": 3}`,
+		ours:   `{"a": 1, "c": 3}`,
+		theirs: `{"a": 1, "b": 20, "c": 3}`,
+		want: `<<<<<<< ours
+{"a": 1, "c": 3}
+=======
+{"a": 1, "b": 20, "c": 3}
+>>>>>>> theirs
+`,
+		wantConflicted: true,
+	}, {
+		name:   "Nested",
+		base:   "{\n\t\"a\": {\n\t\t\"x\": 1,\n\t\t\"y\": {\"z\": 1},\n\t},\n}\n",
+		ours:   "{\n\t\"a\": {\n\t\t\"x\": 2,\n\t\t\"y\": {\"z\": 2},\n\t},\n}\n",
+		theirs: "{\n\t\"a\": {\n\t\t\"x\": 3,\n\t\t\"y\": {\"z\": 3},\n\t},\n}\n",
+		want: `{
+	"a": {
+<<<<<<< ours
+		"x": 2,
+		"y": {"z": 2},
+=======
+		"x": 3,
+		"y": {"z": 3},
+>>>>>>> theirs
+	},
+}
+`,
+		wantConflicted: true,
+	}, {
+		name:   "NestedObjectReplaced",
+		base:   "{\n\t\"a\": {\"x\": 1},\n}\n",
+		ours:   "{\n\t\"a\": {\"x\": 1, \"y\": 2},\n}\n",
+		theirs: "{\n\t\"a\": {\n\t\t\"x\": 1,\n\t\t\"y\": 3,\n\t},\n}\n",
+		want: `{
+<<<<<<< ours
+	"a": {"x": 1, "y": 2},
+=======
+	"a": {"x": 1, "y": 3},
+>>>>>>> theirs
+}
+`,
+		wantConflicted: true,
+	}, {
+		name:   "LastMemberTrailingComma",
+		base:   "{\n\t\"a\": 1,\n\t\"b\": 2,\n}\n",
+		ours:   "{\n\t\"a\": 1,\n\t\"b\": 4,\n}\n",
+		theirs: "{\n\t\"a\": 1,\n\t\"b\": 3,\n}\n",
+		want: `{
+	"a": 1,
+<<<<<<< ours
+	"b": 4,
+=======
+	"b": 3,
+>>>>>>> theirs
+}
+`,
+		wantConflicted: true,
+	}, {
+		name:   "LastMemberNoTrailingComma",
+		base:   "{\n\t\"a\": 1,\n\t\"b\": 2\n}\n",
+		ours:   "{\n\t\"a\": 1,\n\t\"b\": 4\n}\n",
+		theirs: "{\n\t\"a\": 1,\n\t\"b\": 3\n}\n",
+		want: `{
+	"a": 1,
+<<<<<<< ours
+	"b": 4
+=======
+	"b": 3
+>>>>>>> theirs
+}
+`,
+		wantConflicted: true,
+	}, {
+		name:   "LastMemberDeletedTrailingComma",
+		base:   "{\n\t\"a\": 1,\n\t\"b\": 2,\n}\n",
+		ours:   "{\n\t\"a\": 1,\n}\n",
+		theirs: "{\n\t\"a\": 1,\n\t\"b\": 3,\n}\n",
+		want: `{
+	"a": 1,
+<<<<<<< ours
+=======
+	"b": 3,
+>>>>>>> theirs
+}
+`,
+		wantConflicted: true,
+	}, {
+		name:   "LastMemberDeletedNoTrailingComma",
+		base:   "{\n\t\"a\": 1,\n\t\"b\": 2\n}\n",
+		ours:   "{\n\t\"a\": 1\n}\n",
+		theirs: "{\n\t\"a\": 1,\n\t\"b\": 3\n}\n",
+		want: `{
+	"a": 1,
+<<<<<<< ours
+=======
+	"b": 3
+>>>>>>> theirs
+}
+`,
+		wantConflicted: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var vals [3]hujson.Value
+			for i, s := range []string{tt.base, tt.ours, tt.theirs} {
+				var err error
+				if vals[i], err = hujson.Parse([]byte(s)); err != nil {
+					t.Fatalf("Parse error: %v", err)
+				}
+			}
+			got, gotConflicted := merge3(vals[0], vals[1], vals[2])
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("merge3 mismatch (-want +got):\n%s\n\ngot:\n%s", diff, got)
+			}
+			if gotConflicted != tt.wantConflicted {
+				t.Errorf("merge3 conflicted = %v, want %v", gotConflicted, tt.wantConflicted)
+			}
+			if !gotConflicted {
+				return
+			}
+			// Each side of the conflict must be valid HuJSON.
+			for _, side := range []string{"ours", "theirs"} {
+				if _, err := hujson.Parse(resolveConflicts(got, side)); err != nil {
+					t.Errorf("resolving with %s: Parse error: %v", side, err)
+				}
+			}
+		})
+	}
+}
+
+// resolveConflicts resolves all conflicts in b by taking the given side.
+func resolveConflicts(b []byte, side string) []byte {
+	var out []byte
+	var section string
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		switch {
+		case bytes.HasPrefix(line, []byte("<<<<<<< ")):
+			section = "ours"
+		case bytes.HasPrefix(line, []byte("=======")):
+			section = "theirs"
+		case bytes.HasPrefix(line, []byte(">>>>>>> ")):
+			section = ""
+		case section == "" || section == side:
+			out = append(out, line...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,403 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// CommentStyle is the syntactic style of a comment.
+type CommentStyle byte
+
+const (
+	LineComment  CommentStyle = '/' // e.g., // comment
+	BlockComment CommentStyle = '*' // e.g., /* comment */
+)
+
+// MarshalText encodes the style as either "line" or "block".
+func (s CommentStyle) MarshalText() ([]byte, error) {
+	switch s {
+	case LineComment:
+		return []byte("line"), nil
+	case BlockComment:
+		return []byte("block"), nil
+	default:
+		return nil, fmt.Errorf("hujson: invalid comment style: %q", byte(s))
+	}
+}
+
+// UnmarshalText decodes the style from either "line" or "block".
+func (s *CommentStyle) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "line":
+		*s = LineComment
+	case "block":
+		*s = BlockComment
+	default:
+		return fmt.Errorf("hujson: invalid comment style: %q", b)
+	}
+	return nil
+}
+
+// Comment is a single comment.
+type Comment struct {
+	// Text is the content of the comment without the comment delimiters
+	// and without surrounding whitespace.
+	Text string `json:"text"`
+	// Style is the style of the comment.
+	Style CommentStyle `json:"style"`
+	// Offset is the offset of the start of the comment,
+	// which is only accurate if the offsets of the value are accurate
+	// (see UpdateOffsets). It is ignored by SetComments.
+	Offset int `json:"offset,omitempty"`
+}
+
+// String formats the comment with its delimiters
+// and without a trailing newline for a line comment.
+// Any occurrence of "*/" in the text of a block comment is escaped as "* /".
+func (c Comment) String() string {
+	s, _ := formatComment(c)
+	return strings.TrimSuffix(s, "\n")
+}
+
+// CommentGroup is the set of comments associated with a value.
+type CommentGroup struct {
+	// Leading are the comments that precede the value
+	// (or the object member name) on the preceding lines.
+	Leading []Comment `json:"leading,omitempty"`
+	// Trailing are the comments that follow the value (and any comma)
+	// starting on the same line.
+	Trailing []Comment `json:"trailing,omitempty"`
+}
+
+// Comments returns the comments associated with the value
+// specified by the JSON pointer (see RFC 6901).
+// It returns an empty group if the value does not exist.
+//
+// Comments are associated with object members and array elements
+// according to the same heuristics used by Patch, such that
+// a comment separated from a value by a blank line is not associated with it.
+// For the root value, all comments before the value are leading comments and
+// all comments after the value are trailing comments.
+func (v *Value) Comments(ptr string) CommentGroup {
+	p, err := parsePointer(ptr)
+	if err != nil {
+		return CommentGroup{}
+	}
+	s, err := v.find(findState{pointer: p})
+	if err != nil {
+		return CommentGroup{}
+	}
+	if s.parent == nil {
+		return CommentGroup{
+			Leading:  parseComments(v.BeforeExtra, v.StartOffset-len(v.BeforeExtra)),
+			Trailing: parseComments(v.AfterExtra, v.EndOffset),
+		}
+	}
+	return v.FindPointer(p.Parent()).ChildComments(s.idx)
+}
+
+// ChildComments returns the comments associated with the i-th member
+// of an object or the i-th element of an array (see Comments).
+// Unlike Comments, it does not need to resolve a pointer from the root
+// and can report the comments of a member whose name is shared with
+// a preceding member. It returns an empty group if v is not
+// an object or array or if i is out of range.
+func (v *Value) ChildComments(i int) CommentGroup {
+	comp, ok := v.Value.(composite)
+	if !ok || i < 0 || i >= comp.length() {
+		return CommentGroup{}
+	}
+	leading := *comp.beforeExtraAt(i + 0)
+	_, currStart := leading.classifyComments()
+	trailing := *comp.beforeExtraAt(i + 1)
+	prevEnd, _ := trailing.classifyComments()
+	return CommentGroup{
+		Leading:  parseComments(leading[currStart:], extraOffset(v, i+0)+currStart),
+		Trailing: parseComments(trailing[:prevEnd], extraOffset(v, i+1)),
+	}
+}
+
+// SetComments replaces the comments associated with the value
+// specified by the JSON pointer (see RFC 6901) with those in g.
+// Leading comments are placed on their own lines before the value,
+// while trailing comments are placed on the same line after the value.
+// See Comments for how comments are associated with a value.
+// Since the heuristics only associate a single trailing line comment
+// with a value unless followed by a blank line, trailing comments that
+// do not end with a line comment may not be reported by Comments.
+//
+// It reports an error if the value does not exist, if any comment text
+// is invalid UTF-8, or if the text of a line comment contains a newline.
+// Any occurrence of "*/" in the text of a block comment is escaped as "* /".
+// It does not format the value. It is recommended that Format be called after.
+func (v *Value) SetComments(ptr string, g CommentGroup) error {
+	leading, err := formatComments(g.Leading, "\n")
+	if err != nil {
+		return fmt.Errorf("hujson: cannot set comments at %q: %v", ptr, err)
+	}
+	trailing, err := formatComments(g.Trailing, " ")
+	if err != nil {
+		return fmt.Errorf("hujson: cannot set comments at %q: %v", ptr, err)
+	}
+	s, err := v.findPath(nil, ptr)
+	if err != nil {
+		return fmt.Errorf("hujson: cannot set comments at %q: %v", ptr, err)
+	}
+
+	if s.parent == nil {
+		v.BeforeExtra = leading
+		v.AfterExtra = nil
+		if len(trailing) > 0 {
+			v.AfterExtra = append(append(Extra{' '}, trailing...), '\n')
+		}
+		return nil
+	}
+
+	s.parent.beforeExtraAt(s.idx + 0).replaceLeadingComments(leading)
+	b := s.parent.beforeExtraAt(s.idx + 1)
+	b.extractTrailingcomments(false)
+	if len(trailing) > 0 {
+		if bytes.Count(trailing, newline) > 1 {
+			// Multiple lines are only associated with the preceding value
+			// if separated from subsequent comments by a blank line.
+			trailing = append(trailing, '\n')
+		}
+		b.injectTrailingComments(append(Extra{' '}, trailing...))
+	}
+	return nil
+}
+
+// parseComments parses all comments in b, which starts at the given offset.
+func parseComments(b Extra, offset int) (comments []Comment) {
+	for n := 0; len(b) > n; {
+		n += consumeWhitespace(b[n:])
+		nc := consumeComment(b[n:])
+		if nc <= 0 {
+			break
+		}
+		c := b[n : n+nc]
+		switch {
+		case bytes.HasPrefix(c, lineCommentStart):
+			c = c[len(lineCommentStart) : len(c)-len(lineCommentEnd)]
+			comments = append(comments, Comment{strings.TrimSpace(string(c)), LineComment, offset + n})
+		case bytes.HasPrefix(c, blockCommentStart):
+			c = c[len(blockCommentStart) : len(c)-len(blockCommentEnd)]
+			comments = append(comments, Comment{strings.TrimSpace(string(c)), BlockComment, offset + n})
+		}
+		n += nc
+	}
+	return comments
+}
+
+// formatComments formats comments separated by sep.
+// A line comment is always terminated by a newline.
+func formatComments(comments []Comment, sep string) (Extra, error) {
+	var b Extra
+	for _, c := range comments {
+		if len(b) > 0 && !bytes.HasSuffix(b, newline) {
+			b = append(b, sep...)
+		}
+		s, err := formatComment(c)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, s...)
+		if c.Style != LineComment && sep == "\n" {
+			b = append(b, '\n')
+		}
+	}
+	return b, nil
+}
+
+// formatComment formats a single comment.
+func formatComment(c Comment) (string, error) {
+	if !utf8.ValidString(c.Text) {
+		return "", fmt.Errorf("invalid UTF-8 in comment %q", c.Text)
+	}
+	var text string
+	if c.Text != "" {
+		text = " " + c.Text
+	}
+	switch c.Style {
+	case LineComment:
+		if strings.ContainsAny(c.Text, "\r\n") {
+			return "", fmt.Errorf("line comment contains a newline: %q", c.Text)
+		}
+		return "//" + text + "\n", nil
+	case BlockComment:
+		return "/*" + strings.ReplaceAll(text, "*/", "* /") + " */", nil
+	default:
+		return "", fmt.Errorf("invalid comment style: %q", c.Style)
+	}
+}
+
+// extraOffset returns the offset of the start of the i-th Extra
+// in the object or array v as returned by composite.beforeExtraAt.
+func extraOffset(v *Value, i int) int {
+	switch comp := v.Value.(type) {
+	case *Object:
+		if i < comp.length() {
+			return comp.Members[i].Name.StartOffset - len(comp.Members[i].Name.BeforeExtra)
+		}
+	case *Array:
+		if i < comp.length() {
+			return comp.Elements[i].StartOffset - len(comp.Elements[i].BeforeExtra)
+		}
+	}
+	return v.EndOffset - len("}") - len(*v.Value.(composite).afterExtra())
+}
+
+// Annotations returns the annotations in the comments associated with
+// the value specified by the JSON pointer (see RFC 6901).
+// It returns nil if there are none or if the value does not exist.
+//
+// An annotation is a line within a leading or trailing comment that
+// starts with "@" and a key, optionally followed by whitespace and a value
+// that extends to the end of the line. For example:
+//
+//	// @deprecated use /new/path
+//	// @secret
+//	"password": "hunter2",
+//
+// has the annotations {"deprecated": "use /new/path", "secret": ""}.
+// A key consists of letters, digits, '_', '-', and '.'.
+// Lines within a block comment may be prefixed with '*'.
+// If a key occurs multiple times, the first annotation is used.
+func (v *Value) Annotations(ptr string) map[string]string {
+	var annotations map[string]string
+	g := v.Comments(ptr)
+	for _, c := range append(g.Leading, g.Trailing...) {
+		for _, line := range strings.Split(c.Text, "\n") {
+			line = strings.TrimSpace(line)
+			if c.Style == BlockComment {
+				line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+			}
+			key, value, ok := parseAnnotation(line)
+			if !ok {
+				continue
+			}
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			if _, ok := annotations[key]; !ok {
+				annotations[key] = value
+			}
+		}
+	}
+	return annotations
+}
+
+// parseAnnotation parses an annotation of the form "@key value".
+func parseAnnotation(line string) (key, value string, ok bool) {
+	if !strings.HasPrefix(line, "@") {
+		return "", "", false
+	}
+	line = line[len("@"):]
+	n := strings.IndexFunc(line, func(r rune) bool {
+		isKey := r == '_' || r == '-' || r == '.' ||
+			('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')
+		return !isKey
+	})
+	if n < 0 {
+		n = len(line)
+	}
+	key, value = line[:n], line[n:]
+	if key == "" || (value != "" && value[0] != ' ' && value[0] != '\t') {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(value), true
+}
+
+// RewriteComments calls f for every comment in v in document order,
+// replacing each comment with the text returned by f,
+// or removing it if f returns false.
+// The replacement text must consist only of comments and whitespace
+// (e.g., the String of a modified Comment), where an unterminated
+// line comment at the end is automatically terminated with a newline.
+// A removed comment on a line of its own is removed along with its line.
+//
+// It reports an error if any replacement text is invalid,
+// in which case v is left unmodified.
+// It does not format the value. It is recommended that Format be called after.
+func (v *Value) RewriteComments(f func(c Comment) (string, bool)) error {
+	v.UpdateOffsets()
+	type rewrite struct {
+		b      *Extra
+		result Extra
+	}
+	var rewrites []rewrite
+	var err error
+	v.rangeExtras(func(b *Extra, offset int) {
+		if err != nil || !b.hasComment() {
+			return
+		}
+		var out Extra
+		var last int // end of the previous comment in b
+		forEachComment(*b, func(raw Extra, n int) {
+			if err != nil {
+				return
+			}
+			c := parseComments(raw, offset+n)[0]
+			ws := (*b)[last:n]
+			afterNewline := last > 0 && (*b)[last-1] == '\n' // after a line comment
+			last = n + len(raw)
+			rest := (*b)[last:]
+			restNewline := len(rest) - len(bytes.TrimLeft(rest, " \t\r"))
+			if restNewline >= len(rest) || rest[restNewline] != '\n' {
+				restNewline = -1
+			}
+			if s, ok := f(c); ok && Extra(s).hasComment() {
+				r := Extra(s)
+				if !r.IsValid() {
+					r = append(r, '\n') // permit an unterminated line comment
+				}
+				if !r.IsValid() {
+					err = fmt.Errorf("hujson: invalid replacement for comment at offset %d: %q", c.Offset, s)
+					return
+				}
+				out = append(append(out, ws...), r...)
+				if c.Style == BlockComment && bytes.HasSuffix(r, newline) && restNewline >= 0 {
+					last += restNewline + len("\n") // already terminated by a line comment
+				}
+				return
+			}
+
+			// Remove the comment, along with its line if it is on its own.
+			nl := bytes.LastIndexByte(ws, '\n')
+			ownLine := nl >= 0 || afterNewline || offset+n == 0
+			if c.Style == BlockComment {
+				ownLine = ownLine && restNewline >= 0
+				if ownLine {
+					last += restNewline + len("\n")
+				}
+			}
+			switch {
+			case ownLine:
+				out = append(out, ws[:nl+1]...)
+			case c.Style == LineComment:
+				out = append(append(out, bytes.TrimRight(ws, " \t")...), '\n')
+			default:
+				out = append(out, ws...)
+			}
+		})
+		out = append(out, (*b)[last:]...)
+		if out == nil {
+			out = Extra{} // preserve a non-nil Extra (e.g., a trailing comma)
+		}
+		rewrites = append(rewrites, rewrite{b, out})
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range rewrites {
+		*r.b = r.result
+	}
+	v.UpdateOffsets()
+	return nil
+}
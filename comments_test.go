@@ -0,0 +1,273 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestComments(t *testing.T) {
+	const in = `// Root comment.
+{
+	// Unrelated comment.
+
+	// Comment for server.
+	"server": {
+		/* Port number. */ // Line two.
+		"port": 8080, // Trailing for port.
+		"host": "localhost",
+	}, /* Trailing for server. */
+	"list": [1, /* two */ 2],
+} // After root.
+`
+	v, err := Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	tests := []struct {
+		ptr  string
+		want CommentGroup
+	}{{
+		ptr: "",
+		want: CommentGroup{
+			Leading:  []Comment{{"Root comment.", LineComment, 0}},
+			Trailing: []Comment{{"After root.", LineComment, 235}},
+		},
+	}, {
+		ptr: "/server",
+		want: CommentGroup{
+			// A trailing block comment is not associated with the value
+			// unless followed by a blank line.
+			Leading: []Comment{{"Comment for server.", LineComment, 44}},
+		},
+	}, {
+		ptr: "/server/port",
+		want: CommentGroup{
+			Leading: []Comment{
+				{"Port number.", BlockComment, 82},
+				{"Line two.", LineComment, 101},
+			},
+			Trailing: []Comment{{"Trailing for port.", LineComment, 130}},
+		},
+	}, {
+		ptr: "/server/host",
+	}, {
+		ptr:  "/list/1",
+		want: CommentGroup{Leading: []Comment{{"two", BlockComment, 219}}},
+	}, {
+		ptr: "/missing",
+	}}
+	for _, tt := range tests {
+		got := v.Comments(tt.ptr)
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Comments(%q) mismatch (-want +got):\n%s", tt.ptr, diff)
+		}
+		for _, c := range append(got.Leading, got.Trailing...) {
+			if s, _ := formatComment(c); in[c.Offset:][:2] != s[:2] {
+				t.Errorf("Comments(%q): comment %q at offset %d not found in input", tt.ptr, c.Text, c.Offset)
+			}
+		}
+	}
+}
+
+func TestSetComments(t *testing.T) {
+	v, err := Parse([]byte(`{
+	// Old comment.
+	"a": 1, // Old trailing.
+	"b": 2,
+	"c": [3],
+}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	sets := []struct {
+		ptr     string
+		g       CommentGroup
+		wantErr bool
+	}{
+		{ptr: "/a", g: CommentGroup{Leading: []Comment{{Text: "New comment.", Style: LineComment}}}},
+		{ptr: "/b", g: CommentGroup{
+			Leading:  []Comment{{Text: "Block */ comment.", Style: BlockComment}, {Text: "Line.", Style: LineComment}},
+			Trailing: []Comment{{Text: "Two.", Style: LineComment}},
+		}},
+		{ptr: "/c/0", g: CommentGroup{Trailing: []Comment{{Text: "Three.", Style: BlockComment}}}},
+		{ptr: "", g: CommentGroup{Leading: []Comment{{Text: "Root.", Style: LineComment}}}},
+		{ptr: "/a", g: CommentGroup{Leading: []Comment{{Text: "Multi\nline.", Style: LineComment}}}, wantErr: true},
+		{ptr: "/a", g: CommentGroup{Leading: []Comment{{Text: "\xff", Style: BlockComment}}}, wantErr: true},
+		{ptr: "/missing", wantErr: true},
+	}
+	for _, tt := range sets {
+		if err := v.SetComments(tt.ptr, tt.g); (err != nil) != tt.wantErr {
+			t.Errorf("SetComments(%q) error = %v, want error %v", tt.ptr, err, tt.wantErr)
+		}
+	}
+	v.Format()
+	got := v.String()
+	want := `// Root.
+{
+	// New comment.
+	"a": 1,
+	/* Block * / comment. */
+	// Line.
+	"b": 2, // Two.
+	"c": [3 /* Three. */ ],
+}
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("SetComments mismatch (-want +got):\n%s\n\ngot:\n%s", diff, got)
+	}
+	if diff := cmp.Diff(CommentGroup{
+		Leading:  []Comment{{Text: "Block * / comment.", Style: BlockComment}, {Text: "Line.", Style: LineComment}},
+		Trailing: []Comment{{Text: "Two.", Style: LineComment}},
+	}, v.Comments("/b"), cmp.FilterPath(func(p cmp.Path) bool { return p.Last().String() == ".Offset" }, cmp.Ignore())); diff != "" {
+		t.Errorf("Comments mismatch after SetComments (-want +got):\n%s", diff)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	v, err := Parse([]byte(`{
+	// The password for the database.
+	// @secret
+	// @deprecated use /db/credentials
+	"password": "hunter2",
+	"timeout": "5s", // @type duration
+	/**
+	 * @since 1.2
+	 * @since 1.3
+	 */
+	"retries": 3,
+	// Contact me@example.com or @ nobody, or @bad!key.
+	"owner": "",
+}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	tests := []struct {
+		ptr  string
+		want map[string]string
+	}{
+		{"/password", map[string]string{"secret": "", "deprecated": "use /db/credentials"}},
+		{"/timeout", map[string]string{"type": "duration"}},
+		{"/retries", map[string]string{"since": "1.2"}},
+		{"/owner", nil},
+		{"/missing", nil},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, v.Annotations(tt.ptr)); diff != "" {
+			t.Errorf("Annotations(%q) mismatch (-want +got):\n%s", tt.ptr, diff)
+		}
+	}
+}
+
+func TestRewriteComments(t *testing.T) {
+	const in = `// TODO: Remove before release.
+{
+	//Normalize me.
+	"a": 1, // TODO: internal note
+	/* TODO: drop */
+	"b": [1, /* two */ 2, /* TODO */ 3],
+	/* Convert
+	   me. */
+	"c": 3,
+}
+`
+	v, err := Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	err = v.RewriteComments(func(c Comment) (string, bool) {
+		switch {
+		case strings.HasPrefix(c.Text, "TODO"):
+			return "", false
+		case c.Text == "two":
+			c.Style = LineComment
+		case c.Style == BlockComment:
+			// Convert each line to a line comment.
+			var lines []string
+			for _, line := range strings.Split(c.Text, "\n") {
+				lines = append(lines, "// "+strings.TrimSpace(line))
+			}
+			return strings.Join(lines, "\n"), true
+		}
+		return c.String(), true
+	})
+	if err != nil {
+		t.Fatalf("RewriteComments error: %v", err)
+	}
+	v.Format()
+	got := v.String()
+	want := `{
+	// Normalize me.
+	"a": 1,
+	"b": [
+		1, // two
+		2,
+		3,
+	],
+	// Convert
+	// me.
+	"c": 3,
+}
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RewriteComments mismatch (-want +got):\n%s\n\ngot:\n%s", diff, got)
+	}
+
+	err = v.RewriteComments(func(c Comment) (string, bool) { return "not a comment", true })
+	if err == nil {
+		t.Errorf("RewriteComments succeeded, want error")
+	}
+	if got2 := v.String(); got2 != got {
+		t.Errorf("RewriteComments mutated value on error:\n%s", got2)
+	}
+}
+
+func TestCommentString(t *testing.T) {
+	tests := []struct {
+		in   Comment
+		want string
+	}{
+		{Comment{Text: "comment", Style: LineComment}, "// comment"},
+		{Comment{Text: "", Style: LineComment}, "//"},
+		{Comment{Text: "comment", Style: BlockComment}, "/* comment */"},
+		{Comment{Text: "!important", Style: BlockComment}, "/* !important */"},
+		{Comment{Text: "a */ b", Style: BlockComment}, "/* a * / b */"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("%#v.String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestChildComments(t *testing.T) {
+	v, err := Parse([]byte(`{
+	// First.
+	"a": 1,
+	// Second.
+	"a": [2, 3], // Trailing.
+}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	for i, want := range []CommentGroup{
+		{Leading: []Comment{{"First.", LineComment, 3}}},
+		{Leading: []Comment{{"Second.", LineComment, 23}}, Trailing: []Comment{{"Trailing.", LineComment, 48}}},
+		{},
+	} {
+		if diff := cmp.Diff(want, v.ChildComments(i)); diff != "" {
+			t.Errorf("ChildComments(%d) mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+	if diff := cmp.Diff(v.Comments("/a"), v.ChildComments(0)); diff != "" {
+		t.Errorf("Comments and ChildComments mismatch (-Comments +ChildComments):\n%s", diff)
+	}
+	if got := v.Value.(*Object).Members[0].Value.ChildComments(0); got.Leading != nil || got.Trailing != nil {
+		t.Errorf("ChildComments on literal = %+v, want empty", got)
+	}
+}
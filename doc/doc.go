@@ -0,0 +1,158 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package doc generates reference documentation for a configuration file
+// from the comments in a sample HuJSON document.
+//
+// Each value in the document is documented by its leading comments,
+// or its trailing comment if it has no leading comments
+// (see hujson.Value.Comments). For example:
+//
+//	{
+//		// The address to listen on.
+//		"addr": "localhost:8080",
+//		"debug": false, // Whether to enable debug logging.
+//	}
+//
+// documents "/addr" as "The address to listen on." with a default value of
+// "localhost:8080", and "/debug" as "Whether to enable debug logging."
+// with a default value of false.
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nrawrx3/hujson"
+)
+
+// Entry documents a single value in a HuJSON document.
+type Entry struct {
+	// Pointer is the JSON pointer to the value (see RFC 6901).
+	Pointer string `json:"pointer"`
+	// Type is the JSON type of the value, which is either
+	// "null", "boolean", "string", "number", "object", or "array".
+	Type string `json:"type"`
+	// Default is the value in the document as minified standard JSON.
+	// It is empty for objects, whose members are documented separately.
+	Default string `json:"default,omitempty"`
+	// Doc is the text of the comments associated with the value,
+	// where each line has surrounding whitespace removed.
+	Doc string `json:"doc,omitempty"`
+}
+
+// Extract returns an entry for every value in v except the root value,
+// in the order that the values appear in the document.
+// Objects and arrays within arrays are included so that, for example,
+// the members of an object in an array of examples are also documented.
+func Extract(v hujson.Value) []Entry {
+	var entries []Entry
+	var walk func(v *hujson.Value, p hujson.Pointer)
+	walk = func(node *hujson.Value, p hujson.Pointer) {
+		switch comp := node.Value.(type) {
+		case *hujson.Object:
+			for i := range comp.Members {
+				name := comp.Members[i].Name.Value.(hujson.Literal).String()
+				p2 := p.Append(name)
+				entries = append(entries, newEntry(&comp.Members[i].Value, p2, node.ChildComments(i)))
+				walk(&comp.Members[i].Value, p2)
+			}
+		case *hujson.Array:
+			for i := range comp.Elements {
+				if _, ok := comp.Elements[i].Value.(hujson.Literal); ok {
+					continue // already documented by the default value of the array
+				}
+				p2 := p.Append(strconv.Itoa(i))
+				entries = append(entries, newEntry(&comp.Elements[i], p2, node.ChildComments(i)))
+				walk(&comp.Elements[i], p2)
+			}
+		}
+	}
+	walk(&v, hujson.Pointer{})
+	return entries
+}
+
+// newEntry returns the entry for v at p with the comments in g.
+func newEntry(v *hujson.Value, p hujson.Pointer, g hujson.CommentGroup) Entry {
+	e := Entry{Pointer: p.String()}
+	switch v.Value.Kind() {
+	case 'n':
+		e.Type = "null"
+	case 't', 'f':
+		e.Type = "boolean"
+	case '"':
+		e.Type = "string"
+	case '0':
+		e.Type = "number"
+	case '{':
+		e.Type = "object"
+	case '[':
+		e.Type = "array"
+	}
+	if e.Type != "object" {
+		v2 := hujson.Value{Value: v.Value}.Clone()
+		v2.Standardize()
+		v2.Minimize()
+		e.Default = string(v2.Pack())
+	}
+	comments := g.Leading
+	if len(comments) == 0 {
+		comments = g.Trailing
+	}
+	var lines []string
+	for _, c := range comments {
+		for _, line := range strings.Split(c.Text, "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	e.Doc = strings.Join(lines, "\n")
+	return e
+}
+
+// WriteMarkdown writes the entries as a Markdown table.
+func WriteMarkdown(w io.Writer, entries []Entry) error {
+	var b strings.Builder
+	b.WriteString("| Option | Type | Default | Description |\n")
+	b.WriteString("| ------ | ---- | ------- | ----------- |\n")
+	for _, e := range entries {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+			markdownCode(e.Pointer), e.Type, markdownCode(e.Default), markdownText(e.Doc))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the entries as an indented JSON array.
+func WriteJSON(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	b, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// markdownCode formats s as a code span within a table cell.
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	s = strings.ReplaceAll(s, "|", `\|`)
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+// markdownText formats s as text within a table cell.
+func markdownText(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package doc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nrawrx3/hujson"
+)
+
+const sample = `// Sample configuration.
+{
+	// The address to listen on.
+	"addr": "localhost:8080",
+	"debug": false, // Whether to enable | debug logging.
+
+	// TLS settings.
+	"tls": {
+		/* The certificate
+		   path. */
+		"cert": null,
+	},
+	"peers": [
+		"a",
+		// An example peer.
+		{"name/id": 1},
+	],
+}
+`
+
+func TestExtract(t *testing.T) {
+	v, err := hujson.Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	got := Extract(v)
+	want := []Entry{
+		{Pointer: "/addr", Type: "string", Default: `"localhost:8080"`, Doc: "The address to listen on."},
+		{Pointer: "/debug", Type: "boolean", Default: "false", Doc: "Whether to enable | debug logging."},
+		{Pointer: "/tls", Type: "object", Doc: "TLS settings."},
+		{Pointer: "/tls/cert", Type: "null", Default: "null", Doc: "The certificate\npath."},
+		{Pointer: "/peers", Type: "array", Default: `["a",{"name/id":1}]`},
+		{Pointer: "/peers/1", Type: "object", Doc: "An example peer."},
+		{Pointer: "/peers/1/name~1id", Type: "number", Default: "1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Extract mismatch (-want +got):\n%s", diff)
+	}
+
+	var b bytes.Buffer
+	if err := WriteMarkdown(&b, got[:4]); err != nil {
+		t.Fatalf("WriteMarkdown error: %v", err)
+	}
+	wantMarkdown := "| Option | Type | Default | Description |\n" +
+		"| ------ | ---- | ------- | ----------- |\n" +
+		"| `/addr` | string | `\"localhost:8080\"` | The address to listen on. |\n" +
+		"| `/debug` | boolean | `false` | Whether to enable \\| debug logging. |\n" +
+		"| `/tls` | object |  | TLS settings. |\n" +
+		"| `/tls/cert` | null | `null` | The certificate<br>path. |\n"
+	if diff := cmp.Diff(wantMarkdown, b.String()); diff != "" {
+		t.Errorf("WriteMarkdown mismatch (-want +got):\n%s", diff)
+	}
+
+	b.Reset()
+	if err := WriteJSON(&b, got[2:3]); err != nil {
+		t.Fatalf("WriteJSON error: %v", err)
+	}
+	wantJSON := "[\n\t{\n\t\t\"pointer\": \"/tls\",\n\t\t\"type\": \"object\",\n\t\t\"doc\": \"TLS settings.\"\n\t}\n]\n"
+	if diff := cmp.Diff(wantJSON, b.String()); diff != "" {
+		t.Errorf("WriteJSON mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExtractDuplicateNames(t *testing.T) {
+	v, err := hujson.Parse([]byte(`{
+	// First.
+	"a": 1,
+	// Second.
+	"a": 2,
+}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	got := Extract(v)
+	want := []Entry{
+		{Pointer: "/a", Type: "number", Default: "1", Doc: "First."},
+		{Pointer: "/a", Type: "number", Default: "2", Doc: "Second."},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Extract mismatch (-want +got):\n%s", diff)
+	}
+}
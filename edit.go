@@ -0,0 +1,317 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// The methods in this file edit objects and arrays in place.
+// Comments are moved along with the members and elements that are
+// inserted, replaced, or removed using the same heuristics as Patch.
+// The presence of a trailing comma after the last member or element
+// is preserved across edits.
+
+// Get returns the value of the first member with the given name,
+// or nil if there is no such member.
+func (obj *Object) Get(name string) *Value {
+	if i := memberIndex(obj, name); i >= 0 {
+		return &obj.Members[i].Value
+	}
+	return nil
+}
+
+// Set replaces the value of the first member with the given name,
+// or appends a new member if there is no such member.
+// The comments of an existing member are replaced by those
+// in v.BeforeExtra and v.AfterExtra, if any.
+func (obj *Object) Set(name string, v Value) {
+	if i := memberIndex(obj, name); i >= 0 {
+		replaceAt(obj, i, v)
+		return
+	}
+	obj.insertMember(obj.length(), name, v)
+}
+
+// Delete removes the first member with the given name along with
+// its associated comments. It reports whether such a member existed.
+func (obj *Object) Delete(name string) bool {
+	i := memberIndex(obj, name)
+	if i < 0 {
+		return false
+	}
+	removePreservingComma(obj, i)
+	return true
+}
+
+// Rename renames the first member with the given old name,
+// leaving its value and comments as is.
+// It reports whether such a member existed.
+func (obj *Object) Rename(oldName, newName string) bool {
+	i := memberIndex(obj, oldName)
+	if i < 0 {
+		return false
+	}
+	obj.Members[i].Name.Value = obj.memberNameLiteral(newName)
+	return true
+}
+
+// InsertAfter inserts a new member after the first member with the given name.
+// It reports whether such a member existed.
+func (obj *Object) InsertAfter(name, newName string, v Value) bool {
+	i := memberIndex(obj, name)
+	if i < 0 {
+		return false
+	}
+	obj.insertMember(i+1, newName, v)
+	return true
+}
+
+func (obj *Object) insertMember(i int, name string, v Value) {
+	lit := obj.memberNameLiteral(name)
+	insertPreservingComma(obj, i, v)
+	obj.Members[i].Name.Value = lit
+}
+
+// memberNameLiteral returns the literal for a new member name,
+// which is unquoted only if all existing member names are unquoted
+// and the name is valid as an unquoted key.
+func (obj *Object) memberNameLiteral(name string) Literal {
+	if obj.length() == 0 || !isUnquotedKeyName(name) {
+		return String(name)
+	}
+	for _, m := range obj.Members {
+		if !m.Name.Value.(Literal).isUnquotedKey() {
+			return String(name)
+		}
+	}
+	return Literal(name)
+}
+
+// isUnquotedKeyName reports whether s may be used as an unquoted key.
+func isUnquotedKeyName(s string) bool {
+	if s == "" || s == "null" || s == "true" || s == "false" {
+		return false
+	}
+	for i, c := range []byte(s) {
+		isAlpha := c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+		if !isAlpha && (i == 0 || !('0' <= c && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// Append appends v as the last element.
+func (arr *Array) Append(v Value) {
+	insertPreservingComma(arr, arr.length(), v)
+}
+
+// Insert inserts v as the i-th element, shifting subsequent elements.
+// It panics if i is not within [0, len(arr.Elements)].
+func (arr *Array) Insert(i int, v Value) {
+	if i < 0 || i > arr.length() {
+		panic("hujson: array index out of range")
+	}
+	insertPreservingComma(arr, i, v)
+}
+
+// Remove removes and returns the i-th element along with
+// its associated comments. It panics if i is out of range.
+func (arr *Array) Remove(i int) Value {
+	if i < 0 || i >= arr.length() {
+		panic("hujson: array index out of range")
+	}
+	return removePreservingComma(arr, i)
+}
+
+// insertPreservingComma is like insertAt, but preserves whether
+// comp has a trailing comma.
+func insertPreservingComma(comp composite, i int, v Value) {
+	trailing := hasTrailingComma(comp)
+	insertAt(comp, i, v)
+	setTrailingComma(comp, trailing)
+}
+
+// removePreservingComma is like removeAt, but preserves whether
+// comp has a trailing comma.
+func removePreservingComma(comp composite, i int) Value {
+	trailing := hasTrailingComma(comp)
+	v := removeAt(comp, i)
+	setTrailingComma(comp, trailing)
+	return v
+}
+
+// SetOption configures the behavior of Value.Set.
+type SetOption func(*setOptions)
+
+type setOptions struct {
+	noCreate bool
+}
+
+// WithoutCreate reports an error from Value.Set if any parent of the value
+// being set does not exist, rather than creating it.
+func WithoutCreate() SetOption {
+	return func(o *setOptions) { o.noCreate = true }
+}
+
+// Set sets the value specified by the JSON pointer (see RFC 6901) to x,
+// which is either a Value, *Value, ValueTrimmed, or any Go value that
+// can be marshaled by the "encoding/json" package.
+//
+// An existing value is replaced, where the comments associated with it
+// are replaced by those in x if x is a Value with comments.
+// A missing object member is appended to the object, and
+// an array index of "-" or equal to the array length appends an element.
+// Missing parent values are created like "mkdir -p", where each is
+// an array if the next reference token is "-", and an object otherwise.
+// Unlike the JSON Patch "add" operation, Set never shifts array elements.
+func (v *Value) Set(ptr string, x interface{}, opts ...SetOption) error {
+	var o setOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	p, err := parsePointer(ptr)
+	if err != nil {
+		return fmt.Errorf("hujson: cannot set %q: %v", ptr, err)
+	}
+	nv, err := toValue(x)
+	if err != nil {
+		return fmt.Errorf("hujson: cannot set %q: %v", ptr, err)
+	}
+
+	s, err := v.find(findState{pointer: p})
+	switch {
+	case err == nil && s.parent == nil:
+		v.Value = nv.Value
+		if nv.BeforeExtra.hasComment() {
+			v.BeforeExtra = nv.BeforeExtra
+		}
+		if nv.AfterExtra.hasComment() {
+			v.AfterExtra = nv.AfterExtra
+		}
+		return nil
+	case err == nil:
+		replaceAt(s.parent, s.idx, nv)
+		return nil
+	case err != errNotFound:
+		return fmt.Errorf("hujson: cannot set %q: %v", ptr, err)
+	case s.depth < len(p.tokens) && o.noCreate:
+		return fmt.Errorf("hujson: cannot set %q: parent %v not found", ptr, Pointer{p.tokens[:s.depth]})
+	}
+
+	// Build any missing parents from the inside out.
+	for i := len(p.tokens) - 1; i >= s.depth; i-- {
+		if p.tokens[i] == "-" {
+			nv = Value{Value: &Array{Elements: []Value{nv}}}
+		} else {
+			nv = Value{Value: &Object{Members: []ObjectMember{{Value{Value: String(p.tokens[i])}, nv}}}}
+		}
+	}
+	switch comp := s.parent.(type) {
+	case *Object:
+		comp.insertMember(comp.length(), s.name, nv)
+	case *Array:
+		if s.name != "-" && s.name != strconv.Itoa(comp.length()) {
+			return fmt.Errorf("hujson: cannot set %q: array index %s out of range", ptr, s.name)
+		}
+		comp.Append(nv)
+	}
+	return nil
+}
+
+// Get decodes the value specified by the JSON pointer (see RFC 6901)
+// as a Go value of the same types that json.Unmarshal would store in
+// an interface{} value. Comments are discarded.
+// If an object has multiple members with the same name, the first is used.
+func (v *Value) Get(ptr string) (interface{}, error) {
+	s, err := v.findPath(nil, ptr)
+	if err != nil {
+		return nil, fmt.Errorf("hujson: cannot get %q: %v", ptr, err)
+	}
+	return decodeValue(s.value)
+}
+
+// decodeValue is like decodeJSON, but reports an error for
+// literals that json.Unmarshal would fail to decode.
+func decodeValue(v *Value) (interface{}, error) {
+	switch v2 := v.Value.(type) {
+	case Literal:
+		if !v2.IsValid() {
+			return nil, fmt.Errorf("hujson: invalid literal: %s", v2)
+		}
+		switch v2.Kind() {
+		case '"':
+			return v2.Str()
+		case '0':
+			return v2.Float64()
+		}
+		return decodeJSON(v), nil
+	case *Object:
+		m := make(map[string]interface{}, len(v2.Members))
+		for i := range v2.Members {
+			name := v2.Members[i].Name.Value.(Literal).memberName()
+			if _, ok := m[name]; !ok {
+				x, err := decodeValue(&v2.Members[i].Value)
+				if err != nil {
+					return nil, err
+				}
+				m[name] = x
+			}
+		}
+		return m, nil
+	case *Array:
+		s := make([]interface{}, len(v2.Elements))
+		for i := range v2.Elements {
+			x, err := decodeValue(&v2.Elements[i])
+			if err != nil {
+				return nil, err
+			}
+			s[i] = x
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("hujson: invalid value")
+}
+
+// Delete removes the value specified by the JSON pointer (see RFC 6901)
+// along with its associated comments.
+// It reports an error if the value does not exist or is the root value.
+func (v *Value) Delete(ptr string) error {
+	s, err := v.findPath(nil, ptr)
+	if err != nil {
+		return fmt.Errorf("hujson: cannot delete %q: %v", ptr, err)
+	}
+	if s.parent == nil {
+		return fmt.Errorf("hujson: cannot delete root value")
+	}
+	removePreservingComma(s.parent, s.idx)
+	return nil
+}
+
+// toValue converts x to a Value, which must be a Value, *Value,
+// ValueTrimmed, or a Go value that can be marshaled as JSON.
+// As with json.Marshal, a nil *Value is converted to a JSON null.
+// The result never aliases x.
+func toValue(x interface{}) (Value, error) {
+	switch x := x.(type) {
+	case Value:
+		return x.Clone(), nil
+	case *Value:
+		if x == nil {
+			return Value{Value: Literal("null")}, nil
+		}
+		return x.Clone(), nil
+	case ValueTrimmed:
+		return Value{Value: x.clone()}, nil
+	}
+	b, err := json.Marshal(x)
+	if err != nil {
+		return Value{}, err
+	}
+	return Parse(b)
+}
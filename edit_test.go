@@ -0,0 +1,262 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testdataEdit = []struct {
+	name string
+	in   string
+	edit func(v *Value)
+	want string
+}{{
+	name: "ObjectSetExisting",
+	in:   `{"a": 1, "b": 2}`,
+	edit: func(v *Value) { v.Value.(*Object).Set("a", Value{Value: Int(3)}) },
+	want: `{"a": 3, "b": 2}`,
+}, {
+	name: "ObjectSetNew",
+	in:   `{"a": 1}`,
+	edit: func(v *Value) { v.Value.(*Object).Set("b", Value{Value: Int(2)}) },
+	want: `{"a": 1, "b": 2}`,
+}, {
+	name: "ObjectSetNewEmpty",
+	in:   `{}`,
+	edit: func(v *Value) { v.Value.(*Object).Set("a", Value{Value: Int(1)}) },
+	want: `{"a": 1}`,
+}, {
+	name: "ObjectSetNewTrailingComma",
+	in: `{
+	"a": 1,
+}`,
+	edit: func(v *Value) { v.Value.(*Object).Set("b", Value{Value: Int(2)}) },
+	want: `{
+	"a": 1,
+	"b": 2,
+}`,
+}, {
+	name: "ObjectSetNewUnquoted",
+	in:   `{a: 1}`,
+	edit: func(v *Value) { v.Value.(*Object).Set("b", Value{Value: Int(2)}) },
+	want: `{a: 1, b: 2}`,
+}, {
+	name: "ObjectDeleteLast",
+	in:   `{"a": 1, "b": 2}`,
+	edit: func(v *Value) { v.Value.(*Object).Delete("b") },
+	want: `{"a": 1}`,
+}, {
+	name: "ObjectDeleteWithComments",
+	in: `{
+	// Comment for a.
+	"a": 1, // Trailing a.
+	"b": 2,
+}`,
+	edit: func(v *Value) { v.Value.(*Object).Delete("a") },
+	want: `{
+	"b": 2,
+}`,
+}, {
+	name: "ObjectDeleteMissing",
+	in:   `{"a": 1}`,
+	edit: func(v *Value) { v.Value.(*Object).Delete("z") },
+	want: `{"a": 1}`,
+}, {
+	name: "ObjectRename",
+	in: `{
+	// Comment for a.
+	"a": 1,
+}`,
+	edit: func(v *Value) { v.Value.(*Object).Rename("a", "b") },
+	want: `{
+	// Comment for a.
+	"b": 1,
+}`,
+}, {
+	name: "ObjectRenameUnquoted",
+	in:   `{a: 1, b: 2}`,
+	edit: func(v *Value) { v.Value.(*Object).Rename("a", "needs quotes") },
+	want: `{"needs quotes": 1, b: 2}`,
+}, {
+	name: "ObjectInsertAfter",
+	in: `{
+	"a": 1,
+	// Comment for c.
+	"c": 3,
+}`,
+	edit: func(v *Value) { v.Value.(*Object).InsertAfter("a", "b", Value{Value: Int(2)}) },
+	want: `{
+	"a": 1,
+	"b": 2,
+	// Comment for c.
+	"c": 3,
+}`,
+}, {
+	name: "ArrayAppend",
+	in:   `[1, 2]`,
+	edit: func(v *Value) { v.Value.(*Array).Append(Value{Value: Int(3)}) },
+	want: `[1, 2, 3]`,
+}, {
+	name: "ArrayAppendTrailingComma",
+	in: `[
+	1,
+]`,
+	edit: func(v *Value) { v.Value.(*Array).Append(Value{Value: Int(2)}) },
+	want: `[
+	1,
+	2,
+]`,
+}, {
+	name: "ArrayInsertFront",
+	in:   `[2, 3]`,
+	edit: func(v *Value) { v.Value.(*Array).Insert(0, Value{Value: Int(1)}) },
+	want: `[1, 2, 3]`,
+}, {
+	name: "ArrayRemoveLast",
+	in:   `[1, 2, 3]`,
+	edit: func(v *Value) { v.Value.(*Array).Remove(2) },
+	want: `[1, 2]`,
+}, {
+	name: "ArrayRemoveLastTrailingComma",
+	in: `[
+	1,
+	2, // Comment for 2.
+]`,
+	edit: func(v *Value) { v.Value.(*Array).Remove(1) },
+	want: `[
+	1,
+]`,
+}, {
+	name: "ArrayRemoveOnly",
+	in:   `[1]`,
+	edit: func(v *Value) { v.Value.(*Array).Remove(0) },
+	want: `[]`,
+}}
+
+func TestEdit(t *testing.T) {
+	for _, tt := range testdataEdit {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Parse([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			tt.edit(&v)
+			v.Format()
+			got := v.String()
+			want, err := Format([]byte(tt.want))
+			if err != nil {
+				t.Fatalf("Format error: %v", err)
+			}
+			if diff := cmp.Diff(string(want), got); diff != "" {
+				t.Errorf("edit mismatch (-want +got):\n%s\n\ngot:\n%s", diff, got)
+			}
+		})
+	}
+}
+
+func TestObjectGet(t *testing.T) {
+	v, err := Parse([]byte(`{"a": 1, a: 2}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	obj := v.Value.(*Object)
+	if got := obj.Get("a"); got != &obj.Members[0].Value {
+		t.Errorf("Get(%q) = %p, want %p", "a", got, &obj.Members[0].Value)
+	}
+	if got := obj.Get("z"); got != nil {
+		t.Errorf("Get(%q) = %v, want nil", "z", got)
+	}
+}
+
+func TestSetGetDelete(t *testing.T) {
+	v, err := Parse([]byte(`{
+	// Comment for a.
+	a: 1,
+	list: [1, 2],
+}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	sets := []struct {
+		ptr     string
+		val     interface{}
+		opts    []SetOption
+		wantErr bool
+	}{
+		{ptr: "/a", val: 2},
+		{ptr: "/b/c/d", val: "deep"},
+		{ptr: "/b/c/e", val: Value{Value: Bool(true), BeforeExtra: Extra("\n// Comment for e.\n")}},
+		{ptr: "/list/-", val: 3},
+		{ptr: "/list/0", val: map[string]int{"x": 0}},
+		{ptr: "/list/3", val: 4},
+		{ptr: "/list/5", val: 5, wantErr: true},
+		{ptr: "/servers/-/name", val: "primary"},
+		{ptr: "/missing/x", val: 0, opts: []SetOption{WithoutCreate()}, wantErr: true},
+		{ptr: "/a/x", val: 0, wantErr: true},
+		{ptr: "a", val: 0, wantErr: true},
+		{ptr: "/bad", val: func() {}, wantErr: true},
+		{ptr: "/nil", val: (*Value)(nil)},
+	}
+	for _, tt := range sets {
+		if err := v.Set(tt.ptr, tt.val, tt.opts...); (err != nil) != tt.wantErr {
+			t.Errorf("Set(%q) error = %v, want error %v", tt.ptr, err, tt.wantErr)
+		}
+	}
+	if err := v.Delete("/b/c/d"); err != nil {
+		t.Errorf("Delete error: %v", err)
+	}
+	if err := v.Delete("/b/missing"); err == nil {
+		t.Errorf("Delete(%q) succeeded, want error", "/b/missing")
+	}
+	if err := v.Delete(""); err == nil {
+		t.Errorf("Delete(%q) succeeded, want error", "")
+	}
+
+	v.Format()
+	got := v.String()
+	want, err := Format([]byte(`{
+	// Comment for a.
+	a: 2,
+	list: [{"x": 0}, 2, 3, 4],
+	b: {"c": {
+		// Comment for e.
+		"e": true,
+	}},
+	servers: [{"name": "primary"}],
+	nil: null,
+}`))
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+	if diff := cmp.Diff(string(want), got); diff != "" {
+		t.Errorf("Set mismatch (-want +got):\n%s\n\ngot:\n%s", diff, got)
+	}
+
+	gotList, err := v.Get("/list")
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	wantList := []interface{}{map[string]interface{}{"x": 0.0}, 2.0, 3.0, 4.0}
+	if diff := cmp.Diff(wantList, gotList); diff != "" {
+		t.Errorf("Get mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := v.Get("/list/9"); err == nil {
+		t.Errorf("Get(%q) succeeded, want error", "/list/9")
+	}
+
+	bad := Value{Value: &Array{Elements: []Value{
+		{Value: Literal("1e400")},
+		{Value: Literal("nul")},
+		{Value: Literal(`"\x"`)},
+	}}}
+	for _, ptr := range []string{"/0", "/1", "/2", ""} {
+		if got, err := bad.Get(ptr); err == nil {
+			t.Errorf("Get(%q) = %v, want error", ptr, got)
+		}
+	}
+}
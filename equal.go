@@ -0,0 +1,259 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// EqualOptions configures the behavior of Equal.
+// The zero value compares values semantically as described by Equal.
+type EqualOptions struct {
+	// StrictNumbers requires numbers to be textually identical
+	// rather than numerically equal (e.g., 1.0 does not equal 1).
+	StrictNumbers bool
+	// OrderedMembers requires object members to be in the same order.
+	OrderedMembers bool
+	// DuplicateNames specifies how objects with duplicate member names
+	// are compared.
+	DuplicateNames DuplicateNames
+}
+
+// DuplicateNames specifies how Equal handles objects with
+// multiple members of the same name.
+type DuplicateNames int
+
+const (
+	// RejectDuplicates reports objects with duplicate names as unequal
+	// to any other value, since the meaning of such objects is unspecified
+	// per RFC 8259, section 4.
+	RejectDuplicates DuplicateNames = iota
+	// FirstDuplicate only considers the first member with a given name,
+	// consistent with Find.
+	FirstDuplicate
+	// LastDuplicate only considers the last member with a given name,
+	// consistent with the "encoding/json" package.
+	LastDuplicate
+)
+
+// Equal reports whether x and y are semantically equal,
+// ignoring all whitespace, comments, and trailing commas.
+//
+// By default, strings are equal if they are the same after unescaping
+// (where strings with invalid UTF-8 or unpaired surrogate escapes
+// are never equal), numbers are equal if
+// they have the same exact decimal value (e.g., 1.0, 1, and 1e0 are equal),
+// objects are equal if they have the same set of member names with equal
+// values regardless of order, and objects with duplicate names are unequal.
+// Object member names may be quoted or unquoted.
+func Equal(x, y Value, opts EqualOptions) bool {
+	return opts.equal(x.Value, y.Value)
+}
+
+func (opts EqualOptions) equal(x, y ValueTrimmed) bool {
+	if x == nil || y == nil || x.Kind() != y.Kind() {
+		return false
+	}
+	switch x := x.(type) {
+	case Literal:
+		y := y.(Literal)
+		switch x.Kind() {
+		case '"':
+			return validString(x) && validString(y) && x.String() == y.String()
+		case '0':
+			if opts.StrictNumbers {
+				return string(x) == string(y)
+			}
+			return normalizeNumber(x) == normalizeNumber(y)
+		default:
+			return string(x) == string(y)
+		}
+	case *Object:
+		xm, ok1 := opts.members(x)
+		ym, ok2 := opts.members(y.(*Object))
+		if !ok1 || !ok2 || len(xm) != len(ym) {
+			return false
+		}
+		if opts.OrderedMembers {
+			for i := range xm {
+				if xm[i].name != ym[i].name || !opts.equal(xm[i].value, ym[i].value) {
+					return false
+				}
+			}
+			return true
+		}
+		yIndex := make(map[string]ValueTrimmed, len(ym))
+		for _, m := range ym {
+			yIndex[m.name] = m.value
+		}
+		for _, m := range xm {
+			yv, ok := yIndex[m.name]
+			if !ok || !opts.equal(m.value, yv) {
+				return false
+			}
+		}
+		return true
+	case *Array:
+		y := y.(*Array)
+		if len(x.Elements) != len(y.Elements) {
+			return false
+		}
+		for i := range x.Elements {
+			if !opts.equal(x.Elements[i].Value, y.Elements[i].Value) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+type namedValue struct {
+	name  string
+	value ValueTrimmed
+}
+
+// members returns the members of obj in order after applying the
+// DuplicateNames option. It reports false if a name is invalid UTF-8
+// or if duplicate names are rejected.
+func (opts EqualOptions) members(obj *Object) ([]namedValue, bool) {
+	members := make([]namedValue, 0, len(obj.Members))
+	indexes := make(map[string]int, len(obj.Members))
+	for _, m := range obj.Members {
+		lit := m.Name.Value.(Literal)
+		if !validString(lit) {
+			return nil, false
+		}
+		name := lit.memberName()
+		i, seen := indexes[name]
+		switch {
+		case !seen:
+			indexes[name] = len(members)
+			members = append(members, namedValue{name, m.Value.Value})
+		case opts.DuplicateNames == RejectDuplicates:
+			return nil, false
+		case opts.DuplicateNames == LastDuplicate:
+			members[i].value = m.Value.Value
+		}
+	}
+	return members, true
+}
+
+// validString reports whether the string literal b is valid UTF-8
+// and has no unpaired surrogate escapes.
+func validString(b Literal) bool {
+	return utf8.Valid(b) && !hasLoneSurrogate(b)
+}
+
+// normalizeNumber returns a representation of the JSON number b
+// that is identical for all numbers with the same exact decimal value.
+func normalizeNumber(b Literal) string {
+	s := string(b)
+	var neg bool
+	if strings.HasPrefix(s, "-") {
+		neg, s = true, s[len("-"):]
+	}
+	exp := new(big.Int)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exp.SetString(strings.TrimPrefix(s[i+1:], "+"), 10)
+		s = s[:i]
+	}
+	digits := s
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		digits = s[:i] + s[i+1:]
+		exp.Sub(exp, big.NewInt(int64(len(s)-i-1)))
+	}
+	digits = strings.TrimLeft(digits, "0")
+	trimmed := strings.TrimRight(digits, "0")
+	exp.Add(exp, big.NewInt(int64(len(digits)-len(trimmed))))
+	if trimmed == "" {
+		return "0" // zero regardless of sign or exponent
+	}
+	if neg {
+		trimmed = "-" + trimmed
+	}
+	return trimmed + "e" + exp.String()
+}
+
+// Hash returns a SHA-256 hash of v such that values that are equal
+// according to Equal with the default options have the same hash.
+// Unlike Canonicalize, numbers are hashed by their exact decimal value,
+// so numbers that differ beyond the precision of a float64 hash differently
+// and numbers out of the range of a float64 may be hashed.
+// It reports an error if an object has duplicate member names
+// or if a string is invalid, since such values are never equal.
+func (v Value) Hash() ([sha256.Size]byte, error) {
+	b, err := appendHashInput(nil, v.Value)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(b), nil
+}
+
+// appendHashInput appends an encoding of v to b that is identical
+// for all values that are equal according to Equal with the default options.
+func appendHashInput(b []byte, v ValueTrimmed) ([]byte, error) {
+	switch v := v.(type) {
+	case Literal:
+		switch v.Kind() {
+		case '"':
+			if !validString(v) {
+				return nil, fmt.Errorf("hujson: invalid string %s", v)
+			}
+			return append(b, String(v.String())...), nil
+		case '0':
+			return append(b, normalizeNumber(v)...), nil
+		default:
+			return append(b, v...), nil
+		}
+	case *Object:
+		members := make([]namedValue, 0, len(v.Members))
+		names := make(map[string]bool, len(v.Members))
+		for _, m := range v.Members {
+			lit := m.Name.Value.(Literal)
+			if !validString(lit) {
+				return nil, fmt.Errorf("hujson: invalid member name %s", lit)
+			}
+			name := lit.memberName()
+			if names[name] {
+				return nil, fmt.Errorf("hujson: duplicate member name %q", name)
+			}
+			names[name] = true
+			members = append(members, namedValue{name, m.Value.Value})
+		}
+		sort.Slice(members, func(i, j int) bool { return members[i].name < members[j].name })
+		b = append(b, '{')
+		for i, m := range members {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, String(m.name)...)
+			b = append(b, ':')
+			var err error
+			if b, err = appendHashInput(b, m.value); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	case *Array:
+		b = append(b, '[')
+		for i := range v.Elements {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendHashInput(b, v.Elements[i].Value); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+	}
+	return nil, fmt.Errorf("hujson: invalid value")
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"testing"
+)
+
+var testdataEqual = []struct {
+	x, y string
+	opts EqualOptions
+	want bool
+}{
+	{x: `null`, y: `null`, want: true},
+	{x: `null`, y: `false`, want: false},
+	{x: `true`, y: ` /* comment */ true `, want: true},
+	{x: `"hello"`, y: `"hello"`, want: true},
+	{x: `"` + "\xff" + `"`, y: `"�"`, want: false},
+	{x: `"` + "\xff" + `"`, y: `"` + "\xff" + `"`, want: false},
+	{x: `"\ud800"`, y: `"\ufffd"`, want: false},
+	{x: `{"\ud800": 1}`, y: `{"\ud800": 1}`, want: false},
+	{x: `"\ud83d\ude00"`, y: `"😀"`, want: true},
+	{x: `1`, y: `1.0`, want: true},
+	{x: `1`, y: `1.0`, opts: EqualOptions{StrictNumbers: true}, want: false},
+	{x: `1`, y: `1`, opts: EqualOptions{StrictNumbers: true}, want: true},
+	{x: `100`, y: `1e2`, want: true},
+	{x: `0.015`, y: `15E-3`, want: true},
+	{x: `0`, y: `-0.0e+10`, want: true},
+	{x: `-1`, y: `1`, want: false},
+	{x: `9223372036854775800`, y: `9223372036854775801`, want: false},
+	{x: `1e1000`, y: `10e999`, want: true},
+	{x: `1e1000`, y: `1e1001`, want: false},
+	{x: `[1, 2, 3,]`, y: `[1,2,3]`, want: true},
+	{x: `[1, 2, 3]`, y: `[3, 2, 1]`, want: false},
+	{x: `[1, 2]`, y: `[1, 2, 3]`, want: false},
+	{x: `{"a": 1, "b": 2}`, y: `{b: 2, a: 1.0}`, want: true},
+	{x: `{"a": 1, "b": 2}`, y: `{"b": 2, "a": 1}`, opts: EqualOptions{OrderedMembers: true}, want: false},
+	{x: `{"a": 1, "b": 2}`, y: `{a: 1, b: 2,}`, opts: EqualOptions{OrderedMembers: true}, want: true},
+	{x: `{"a": 1, "b": 2}`, y: `{"a": 1}`, want: false},
+	{x: `{"a": 1}`, y: `{"b": 1}`, want: false},
+	{x: `{"a": 1, "a": 2}`, y: `{"a": 2}`, want: false},
+	{x: `{"a": 1, "a": 2}`, y: `{"a": 1, "a": 2}`, want: false},
+	{x: `{"a": 1, "a": 2}`, y: `{"a": 1}`, opts: EqualOptions{DuplicateNames: FirstDuplicate}, want: true},
+	{x: `{"a": 1, "a": 2}`, y: `{"a": 2}`, opts: EqualOptions{DuplicateNames: LastDuplicate}, want: true},
+	{x: `{"a": 1, "b": 0, "a": 2}`, y: `{"a": 2, "b": 0}`, opts: EqualOptions{DuplicateNames: LastDuplicate, OrderedMembers: true}, want: true},
+	{x: `{"a": [{"b": null}]}`, y: `{"a": [{"b": null}]}`, want: true},
+	{x: `{"a": [{"b": null}]}`, y: `{"a": [{"b": false}]}`, want: false},
+}
+
+func TestEqual(t *testing.T) {
+	for _, tt := range testdataEqual {
+		x, err := Parse([]byte(tt.x))
+		if err != nil {
+			t.Fatalf("Parse(%s) error: %v", tt.x, err)
+		}
+		y, err := Parse([]byte(tt.y))
+		if err != nil {
+			t.Fatalf("Parse(%s) error: %v", tt.y, err)
+		}
+		if got := Equal(x, y, tt.opts); got != tt.want {
+			t.Errorf("Equal(%s, %s, %+v) = %v, want %v", tt.x, tt.y, tt.opts, got, tt.want)
+		}
+		if got := Equal(y, x, tt.opts); got != tt.want {
+			t.Errorf("Equal(%s, %s, %+v) = %v, want %v", tt.y, tt.x, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	tests := []struct {
+		x, y     string
+		wantSame bool
+		wantErr  bool
+	}{
+		{x: `{"a": 1, "b": [true, null]}`, y: `{
+	// Comment.
+	b: [true, null,],
+	a: 1.0,
+}`, wantSame: true},
+		{x: `"hello"`, y: `"hello"`, wantSame: true},
+		{x: `{"a": 1}`, y: `{"a": 2}`, wantSame: false},
+		{x: `[1, 2]`, y: `[2, 1]`, wantSame: false},
+		{x: `{"a": 1, "a": 2}`, wantErr: true},
+		{x: `1e1000`, y: `10e999`, wantSame: true},
+		{x: `9007199254740993`, y: `9007199254740992`, wantSame: false},
+		{x: `-0.0`, y: `0`, wantSame: true},
+		{x: `{"a": 1, "b": 2}`, y: `{"ab": 1, "": 2}`, wantSame: false},
+		{x: `"\ud800"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		x, err := Parse([]byte(tt.x))
+		if err != nil {
+			t.Fatalf("Parse(%s) error: %v", tt.x, err)
+		}
+		before := x.String()
+		hx, err := x.Hash()
+		if x.String() != before {
+			t.Errorf("Hash mutated value:\ngot:  %s\nwant: %s", x.String(), before)
+		}
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Hash(%s) error = nil, want non-nil", tt.x)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Hash(%s) error: %v", tt.x, err)
+		}
+		y, err := Parse([]byte(tt.y))
+		if err != nil {
+			t.Fatalf("Parse(%s) error: %v", tt.y, err)
+		}
+		hy, err := y.Hash()
+		if err != nil {
+			t.Fatalf("Hash(%s) error: %v", tt.y, err)
+		}
+		if gotSame := hx == hy; gotSame != tt.wantSame {
+			t.Errorf("Hash(%s) == Hash(%s): got %v, want %v", tt.x, tt.y, gotSame, tt.wantSame)
+		}
+		if gotEqual := Equal(x, y, EqualOptions{}); gotEqual != tt.wantSame {
+			t.Errorf("Equal(%s, %s) = %v, want %v", tt.x, tt.y, gotEqual, tt.wantSame)
+		}
+	}
+}
@@ -20,58 +20,79 @@ var errNotFound = fmt.Errorf("value not found")
 // the first is returned. Object names are matched exactly,
 // rather than with a case-insensitive match.
 func (v *Value) Find(ptr string) *Value {
-	if s, err := v.find(findState{pointer: ptr}); err == nil {
+	p, err := parsePointer(ptr)
+	if err != nil {
+		return nil
+	}
+	return v.FindPointer(p)
+}
+
+// FindPointer is like Find, but takes a parsed pointer.
+func (v *Value) FindPointer(p Pointer) *Value {
+	if s, err := v.find(findState{pointer: p}); err == nil {
 		return s.value
 	}
 	return nil
 }
 
+// findPath parses ptr and finds the value it refers to.
+func (v *Value) findPath(c *findCache, ptr string) (findState, error) {
+	p, err := parsePointer(ptr)
+	if err != nil {
+		return findState{}, err
+	}
+	return v.find(findState{pointer: p, cache: c})
+}
+
 type findState struct {
-	pointer string // pointer[:offset] is the current value, pointer[offset:] is the remainder
-	offset  int
+	pointer Pointer // pointer.tokens[:depth] is the current value, pointer.tokens[depth:] is the remainder
+	depth   int
 
 	parent composite // nil for root pointer
 	name   string    // name into parent to obtain current value
 	idx    int       // idx into parent to obtain current value
 	value  *Value    // the current value
+
+	cache *findCache // optional cache of prior lookups; may be nil
 }
 
 func (v *Value) find(s findState) (findState, error) {
+	// Resume from the longest previously resolved prefix of the pointer.
+	if s.cache != nil && s.depth == 0 {
+		if s2, ok := s.cache.lookup(s.pointer); ok {
+			s, v = s2, s2.value
+		}
+	}
+
 	// An empty pointer denotes the value itself.
 	s.value = v
-	if s.pointer[s.offset:] == "" {
+	if s.cache != nil {
+		s.cache.store(s)
+	}
+	if s.depth == len(s.pointer.tokens) {
 		return s, nil
 	}
 	comp, ok := v.Value.(composite)
 	if !ok {
-		return s, fmt.Errorf("invalid pointer: cannot index into literal at %v", s.pointer[:s.offset])
+		return s, fmt.Errorf("invalid pointer: cannot index into literal at %v", Pointer{s.pointer.tokens[:s.depth]})
 	}
-
-	// There must be one or more fragments.
-	s.parent, s.idx, s.name = nil, 0, ""
-	if !strings.HasPrefix(s.pointer[s.offset:], "/") {
-		return s, fmt.Errorf("invalid pointer: lacks a forward slash prefix")
-	}
-	n := len("/")
-	if i := strings.IndexByte(s.pointer[s.offset+n:], '/'); i >= 0 {
-		n += i
-	} else {
-		n = len(s.pointer) - s.offset
-	}
-	s.offset += n
-
-	// Unescape the name if necessary (section 4).
-	name := s.pointer[s.offset-n : s.offset]
-	if strings.IndexByte(name, '~') >= 0 {
-		name = strings.ReplaceAll(name, "~1", "/")
-		name = strings.ReplaceAll(name, "~0", "~")
-	}
-	name = name[len("/"):]
+	name := s.pointer.tokens[s.depth]
+	s.depth++
 
 	// Index into the object or array.
+	if s.cache != nil {
+		comp = s.cache.existingTree(comp)
+	}
 	s.parent, s.name, s.idx = comp, name, comp.length()
-	switch comp := v.Value.(type) {
+	switch comp := comp.(type) {
 	case *Object:
+		if s.cache != nil {
+			if i, ok := s.cache.memberIndex(comp, name); ok {
+				s.idx = i
+				return comp.Members[i].Value.find(s)
+			}
+			break
+		}
 		for i, m := range comp.Members {
 			if m.Name.Value.(Literal).equalString(name) {
 				s.idx = i
@@ -90,11 +111,27 @@ func (v *Value) find(s findState) (findState, error) {
 			s.idx = int(i)
 			return comp.Elements[i].find(s)
 		}
+	case *arrayTree:
+		if name == "-" {
+			return s, errNotFound
+		}
+		i, err := strconv.ParseUint(name, 10, 0)
+		if err != nil || (i == 0 && name != "0") {
+			return s, fmt.Errorf("invalid array index: %s", name)
+		}
+		if i < uint64(comp.length()) {
+			s.idx = int(i)
+			return comp.valueAt(int(i)).find(s)
+		}
 	}
 	return s, errNotFound
 }
 
 func (b Literal) equalString(s string) bool {
+	// Unquoted keys never contain escape characters.
+	if b.isUnquotedKey() {
+		return string(b) == s
+	}
 	// Fast-path: Assume there are no escape characters.
 	if len(b) >= 2 && b[0] == '"' && b[len(b)-1] == '"' && bytes.IndexByte(b, '\\') < 0 {
 		return string(b[len(`"`):len(b)-len(`"`)]) == s
@@ -104,3 +141,296 @@ func (b Literal) equalString(s string) bool {
 	var s2 string
 	return json.Unmarshal(b, &s2) == nil && s == s2
 }
+
+// isPattern reports whether p contains any wildcard ("*")
+// or predicate ("[name=value]") reference tokens.
+func (p Pointer) isPattern() bool {
+	for _, token := range p.tokens {
+		if _, _, ok := parsePredicateToken(token); ok || token == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePredicateToken parses a reference token of the form "[name=value]".
+func parsePredicateToken(token string) (name, value string, ok bool) {
+	if len(token) < len("[=]") || token[0] != '[' || token[len(token)-1] != ']' {
+		return "", "", false
+	}
+	name, value, ok = strings.Cut(token[len("["):len(token)-len("]")], "=")
+	return name, value, ok && name != ""
+}
+
+// expandPattern returns pointers to all values that match
+// the pattern (see PatchOptions.AllowPatterns) in document order.
+// The final reference token need not exist if it is not a pattern.
+// If an object has multiple members with the same name,
+// only the first is matched.
+func (v *Value) expandPattern(pattern Pointer) []Pointer {
+	var ptrs []Pointer
+	var expand func(v *Value, ptr Pointer, rest []string)
+	expand = func(v *Value, ptr Pointer, rest []string) {
+		if len(rest) == 0 {
+			ptrs = append(ptrs, ptr)
+			return
+		}
+		token, rest := rest[0], rest[1:]
+		name, value, isPredicate := parsePredicateToken(token)
+		if token != "*" && !isPredicate {
+			if len(rest) == 0 {
+				ptrs = append(ptrs, ptr.Append(token))
+			} else if s, err := v.find(findState{pointer: Pointer{[]string{token}}}); err == nil {
+				expand(s.value, ptr.Append(token), rest)
+			}
+			return
+		}
+		switch comp := v.Value.(type) {
+		case *Object:
+			for i := range comp.Members {
+				name2 := comp.Members[i].Name.Value.(Literal).memberName()
+				if memberIndex(comp, name2) != i || (isPredicate && !matchPredicate(comp.Members[i].Value, name, value)) {
+					continue
+				}
+				expand(&comp.Members[i].Value, ptr.Append(name2), rest)
+			}
+		case *Array:
+			for i := range comp.Elements {
+				if isPredicate && !matchPredicate(comp.Elements[i], name, value) {
+					continue
+				}
+				expand(&comp.Elements[i], ptr.Append(strconv.Itoa(i)), rest)
+			}
+		}
+	}
+	expand(v, Pointer{}, pattern.tokens)
+	return ptrs
+}
+
+// matchPredicate reports whether v is an object with a member of the given
+// name whose value is the given string or raw literal.
+func matchPredicate(v Value, name, value string) bool {
+	obj, ok := v.Value.(*Object)
+	if !ok {
+		return false
+	}
+	i := memberIndex(obj, name)
+	if i < 0 {
+		return false
+	}
+	lit, ok := obj.Members[i].Value.Value.(Literal)
+	if !ok {
+		return false
+	}
+	if lit.Kind() == '"' {
+		return lit.String() == value
+	}
+	return string(lit) == value
+}
+
+// memberName returns the unescaped name of an object member.
+func (b Literal) memberName() string {
+	switch {
+	case b.isUnquotedKey():
+		return string(b)
+	case len(b) >= 2 && b[0] == '"' && b[len(b)-1] == '"' && bytes.IndexByte(b, '\\') < 0:
+		return string(b[len(`"`) : len(b)-len(`"`)])
+	default:
+		return b.String()
+	}
+}
+
+// minIndexedMembers is the minimum number of members that an object must have
+// before findCache builds an index of names for it.
+// Smaller objects are faster to search linearly.
+const minIndexedMembers = 16
+
+// findCache caches state across a sequence of related pointer lookups
+// against the same root value, such as when applying a patch.
+// Callers must report every structural mutation of a composite value
+// (e.g., insertion, removal, or replacement of a member or element)
+// so that stale state can be discarded.
+//
+// The cache tracks the chain of values along the most recently resolved
+// pointer so that a subsequent lookup for a related pointer only needs
+// to resolve the path segments after the longest common prefix.
+// It also maintains a map of names to indexes for large objects
+// so that name lookup takes O(1) rather than O(n), and represents
+// large arrays that are resized as an arrayTree so that insertion and
+// removal take O(log n) rather than O(n).
+//
+// Since an arrayTree supersedes the Array.Elements of its array,
+// callers must flush the trees for a value before operating on that
+// value without the cache, and must flush all trees when done.
+type findCache struct {
+	pointer Pointer     // most recently resolved pointer
+	states  []findState // states along pointer, ordered by increasing depth
+	names   map[*Object]map[string]int
+	trees   map[*Array]*arrayTree
+}
+
+// lookup returns the deepest cached state for a prefix of p.
+func (c *findCache) lookup(p Pointer) (findState, bool) {
+	i := len(c.states)
+	for i > 0 && !p.hasPrefix(Pointer{c.pointer.tokens[:c.states[i-1].depth]}) {
+		i--
+	}
+	c.states = c.states[:i]
+	c.pointer = p
+	if i == 0 {
+		return findState{}, false
+	}
+	s := c.states[i-1]
+	s.pointer = p
+	return s, true
+}
+
+// store records s as the most recently resolved state.
+func (c *findCache) store(s findState) {
+	if !samePointer(s.pointer, c.pointer) {
+		c.states, c.pointer = c.states[:0], s.pointer
+	}
+	i := len(c.states)
+	for i > 0 && c.states[i-1].depth >= s.depth {
+		i--
+	}
+	c.states = append(c.states[:i], s)
+}
+
+// samePointer reports whether x and y are the same pointer.
+func samePointer(x, y Pointer) bool {
+	return len(x.tokens) == len(y.tokens) && x.hasPrefix(y)
+}
+
+// mutated reports that a member or element of comp was inserted, removed,
+// or replaced, invalidating all cached state for values within comp.
+// A nil comp reports that the root value itself was replaced.
+func (c *findCache) mutated(comp composite) {
+	if t, ok := comp.(*arrayTree); ok {
+		comp = t.arr
+	}
+	for i, s := range c.states {
+		if comp2, ok := s.value.Value.(composite); ok && comp != nil && comp2 == comp {
+			c.states = c.states[:i+1]
+			return
+		}
+	}
+	c.states = c.states[:0]
+}
+
+// existingTree returns the arrayTree for comp if one exists,
+// otherwise it returns comp as is.
+func (c *findCache) existingTree(comp composite) composite {
+	if arr, ok := comp.(*Array); ok {
+		if t, ok := c.trees[arr]; ok {
+			return t
+		}
+	}
+	return comp
+}
+
+// resizable returns a representation of comp that is efficient
+// for the insertion and removal of members or elements.
+// Large arrays are converted to an arrayTree.
+func (c *findCache) resizable(comp composite) composite {
+	if arr, ok := comp.(*Array); ok && len(arr.Elements) >= minTreeElements {
+		if c.trees == nil {
+			c.trees = make(map[*Array]*arrayTree)
+		}
+		t := newArrayTree(arr)
+		c.trees[arr] = t
+		return t
+	}
+	return comp
+}
+
+// flushWithin flushes the trees for all arrays within v
+// so that it may be operated upon without the cache.
+func (c *findCache) flushWithin(v *Value) {
+	if len(c.trees) == 0 {
+		return
+	}
+	switch v2 := v.Value.(type) {
+	case *Object:
+		v2.rangeValues(func(v *Value) bool {
+			c.flushWithin(v)
+			return true
+		})
+	case *Array:
+		if t, ok := c.trees[v2]; ok {
+			t.flush()
+			delete(c.trees, v2)
+			c.mutated(v2) // pointers into the tree are now stale
+		}
+		v2.rangeValues(func(v *Value) bool {
+			c.flushWithin(v)
+			return true
+		})
+	}
+}
+
+// flush flushes the trees for all arrays.
+func (c *findCache) flush() {
+	for arr, t := range c.trees {
+		t.flush()
+		delete(c.trees, arr)
+	}
+	c.states = c.states[:0]
+}
+
+// memberIndex returns the index of the first member in obj with the given name.
+func (c *findCache) memberIndex(obj *Object, name string) (int, bool) {
+	if len(obj.Members) < minIndexedMembers {
+		for i, m := range obj.Members {
+			if m.Name.Value.(Literal).equalString(name) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	m, ok := c.names[obj]
+	if !ok {
+		m = make(map[string]int, len(obj.Members))
+		for i := len(obj.Members) - 1; i >= 0; i-- {
+			m[obj.Members[i].Name.Value.(Literal).memberName()] = i
+		}
+		if c.names == nil {
+			c.names = make(map[*Object]map[string]int)
+		}
+		c.names[obj] = m
+	}
+	i, ok := m[name]
+	return i, ok
+}
+
+// insertedMember reports that obj.Members[i] was inserted.
+func (c *findCache) insertedMember(obj *Object, i int) {
+	if m, ok := c.names[obj]; ok {
+		if i < len(obj.Members)-1 {
+			delete(c.names, obj) // rebuild lazily
+			return
+		}
+		name := obj.Members[i].Name.Value.(Literal).memberName()
+		if _, ok := m[name]; !ok {
+			m[name] = i
+		}
+	}
+}
+
+// removingMember reports that obj.Members[i] is about to be removed.
+func (c *findCache) removingMember(obj *Object, i int) {
+	if m, ok := c.names[obj]; ok {
+		if name := obj.Members[i].Name.Value.(Literal).memberName(); m[name] == i {
+			delete(m, name)
+		}
+		// Shift the indexes of all subsequent members down by one.
+		// If the removed member was the first of duplicate names,
+		// then the next member with that name takes its place.
+		for j := i + 1; j < len(obj.Members); j++ {
+			name := obj.Members[j].Name.Value.(Literal).memberName()
+			if k, ok := m[name]; !ok || k == j {
+				m[name] = j - 1
+			}
+		}
+	}
+}
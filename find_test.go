@@ -58,3 +58,26 @@ func TestFind(t *testing.T) {
 		}
 	}
 }
+
+func TestFindUnquoted(t *testing.T) {
+	v, err := Parse([]byte(`{pos: {x: 1, "y": 2}}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	pos := &v.Value.(*Object).Members[0].Value
+	tests := []struct {
+		ptr  string
+		want *Value
+	}{
+		{"/pos", pos},
+		{"/pos/x", &pos.Value.(*Object).Members[0].Value},
+		{"/pos/y", &pos.Value.(*Object).Members[1].Value},
+		{"/pos/z", nil},
+	}
+	for _, tt := range tests {
+		got := v.Find(tt.ptr)
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Find(%q) mismatch (-want +got):\n%s", tt.ptr, diff)
+		}
+	}
+}
@@ -6,13 +6,18 @@ package hujson
 
 import (
 	"bytes"
+	"fmt"
 	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 // Standardize strips any features specific to HuJSON from b,
 // making it compliant with standard JSON per RFC 8259.
 // All comments and trailing commas are replaced with a space character
 // in order to preserve the original line numbers and byte offsets.
+// However, quoting unquoted keys inserts two bytes per key.
+// Use StandardizeWithSourceMap to translate offsets back to the original.
 // If an error is encountered, then b is returned as is along with the error.
 func Standardize(b []byte) ([]byte, error) {
 	ast, err := Parse(b)
@@ -52,6 +57,37 @@ func Format(b []byte) ([]byte, error) {
 	return ast.Pack(), nil
 }
 
+// FormatWithOptions is like Format, but applies the
+// additional normalizations selected by opts.
+// If an error is encountered, then b is returned as is along with the error.
+func FormatWithOptions(b []byte, opts FormatOptions) ([]byte, error) {
+	ast, err := Parse(b)
+	if err != nil {
+		return b, err
+	}
+	ast.FormatWithOptions(opts)
+	return ast.Pack(), nil
+}
+
+// FormatOptions configures additional normalizations performed by
+// Value.FormatWithOptions. Regardless of these options,
+// formatting always removes unnecessary escapes from strings
+// (e.g., "\u0041" is formatted as "A").
+type FormatOptions struct {
+	// CanonicalNumbers formats numbers according to RFC 8785,
+	// section 3.2.2.3 (e.g., 1.0e+02 is formatted as 100),
+	// but only if the number is exactly representable as
+	// an IEEE 754 double-precision number.
+	CanonicalNumbers bool
+	// LowercaseExponents formats the exponent marker of numbers as 'e'.
+	LowercaseExponents bool
+	// EscapeInvisible escapes control, formatting, and separator characters
+	// within strings. This includes zero-width and bidirectional control
+	// characters, which can otherwise hide the true content of a string
+	// from a reviewer (e.g., CVE-2021-42574).
+	EscapeInvisible bool
+}
+
 const punchCardWidth = 80
 
 var (
@@ -71,13 +107,19 @@ var (
 // Format is idempotent such that formatting already formatted HuJSON
 // results in no changes.
 func (v *Value) Format() {
+	v.FormatWithOptions(FormatOptions{})
+}
+
+// FormatWithOptions is like Format, but applies the
+// additional normalizations selected by opts.
+func (v *Value) FormatWithOptions(opts FormatOptions) {
 	// Format leading extra.
 	v.BeforeExtra.format(0, formatOptions{})
 	v.BeforeExtra = v.BeforeExtra[consumeWhitespace(v.BeforeExtra):] // never has leading whitespace
 	// Format the value.
 	needExpand := make(map[composite]bool)
 	isStandard := v.IsStandard()
-	v.normalize()
+	v.normalize(opts)
 	v.expandComposites(needExpand)
 	v.formatWhitespace(0, needExpand, isStandard)
 	v.alignObjectValues()
@@ -105,17 +147,40 @@ func (v *Value) Range(f func(v *Value) bool) bool {
 
 // normalize performs simple normalization changes. In particular, it:
 //   - normalizes strings,
+//   - normalizes numbers according to opts,
 //   - normalizes empty objects and arrays as simply {} or [],
 //   - normalizes whitespace between names and colons,
 //   - normalizes whitespace between values and commas.
 //
 // It always returns true to be compatible with composite.rangeValues.
-func (v *Value) normalize() bool {
+func (v *Value) normalize(opts FormatOptions) bool {
 	switch v2 := v.Value.(type) {
 	case Literal:
-		// Normalize string if there are escape characters.
-		if v2.Kind() == '"' && bytes.IndexByte(v2, '\\') >= 0 {
-			v.Value = String(v2.String())
+		switch v2.Kind() {
+		case '"':
+			// Normalize string if there are escape characters.
+			if bytes.IndexByte(v2, '\\') >= 0 {
+				v2 = String(v2.String())
+				v.Value = v2
+			}
+			if opts.EscapeInvisible && bytes.IndexFunc(v2, isInvisible) >= 0 {
+				v.Value = escapeInvisible(v2)
+			}
+		case '0':
+			if opts.CanonicalNumbers {
+				if f, err := v2.Float64(); err == nil {
+					if f == 0 {
+						f = 0 // canonicalize negative zero
+					}
+					if f2 := Float(f); normalizeNumber(f2) == normalizeNumber(v2) {
+						v2 = f2
+						v.Value = v2
+					}
+				}
+			}
+			if opts.LowercaseExponents && bytes.IndexByte(v2, 'E') >= 0 {
+				v.Value = Literal(bytes.ReplaceAll(v2, []byte("E"), []byte("e")))
+			}
 		}
 	case composite:
 		// Cleanup for empty objects and arrays.
@@ -137,11 +202,38 @@ func (v *Value) normalize() bool {
 		})
 
 		// Normalize all sub-values.
-		v2.rangeValues((*Value).normalize)
+		v2.rangeValues(func(v *Value) bool { return v.normalize(opts) })
 	}
 	return true
 }
 
+// isInvisible reports whether r is a control, formatting, or separator
+// character that may be invisible or alter the display of surrounding text.
+func isInvisible(r rune) bool {
+	return r != ' ' && unicode.In(r, unicode.Cc, unicode.Cf, unicode.Z)
+}
+
+// escapeInvisible escapes all characters in the string literal b
+// for which isInvisible reports true.
+func escapeInvisible(b Literal) Literal {
+	out := make(Literal, 0, len(b))
+	for len(b) > 0 {
+		r, n := utf8.DecodeRune(b)
+		if isInvisible(r) && r != utf8.RuneError {
+			r1, r2 := utf16.EncodeRune(r)
+			if r1 == unicode.ReplacementChar {
+				out = append(out, fmt.Sprintf(`\u%04x`, r)...)
+			} else {
+				out = append(out, fmt.Sprintf(`\u%04x\u%04x`, r1, r2)...)
+			}
+		} else {
+			out = append(out, b[:n]...)
+		}
+		b = b[n:]
+	}
+	return out
+}
+
 // lineStats carries statistics about a sequence of lines.
 type lineStats struct {
 	firstLength int
@@ -533,3 +533,52 @@ func TestFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatWithOptions(t *testing.T) {
+	tests := []struct {
+		in   string
+		opts FormatOptions
+		want string
+	}{{
+		in:   `[1.0e+02, 1E2, -0.0, 0.5000, 1E400, 9007199254740993, "\u0041"]`,
+		want: `[1.0e+02, 1E2, -0.0, 0.5000, 1E400, 9007199254740993, "A"]`,
+	}, {
+		in:   `[1.0e+02, 1E2, -0.0, 0.5000, 1E400, 9007199254740993, 1e21]`,
+		opts: FormatOptions{CanonicalNumbers: true},
+		want: `[100, 100, 0, 0.5, 1E400, 9007199254740993, 1e+21]`,
+	}, {
+		in:   `[1.0e+02, 1E2, 1E400]`,
+		opts: FormatOptions{LowercaseExponents: true},
+		want: `[1.0e+02, 1e2, 1e400]`,
+	}, {
+		in:   `[1.0E+02, 1E400]`,
+		opts: FormatOptions{CanonicalNumbers: true, LowercaseExponents: true},
+		want: `[100, 1e400]`,
+	}, {
+		in:   "{\"a\u202e\": \"x\u200by\", \"b\": \"\\u2066\", \"c\": \"\U000e0041\", \"d\": \"a b\\u00e9\"}",
+		opts: FormatOptions{EscapeInvisible: true},
+		want: `{"a\u202e": "x\u200by", "b": "\u2066", "c": "\udb40\udc41", "d": "a bé"}`,
+	}, {
+		in:   "[\"\\u202e\"]",
+		want: "[\"\u202e\"]",
+	}}
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			got, err := FormatWithOptions([]byte(tt.in), tt.opts)
+			if err != nil {
+				t.Fatalf("FormatWithOptions error: %v", err)
+			}
+			want := tt.want + "\n"
+			if diff := cmp.Diff(want, string(got)); diff != "" {
+				t.Errorf("FormatWithOptions mismatch (-want +got):\n%s", diff)
+			}
+			got2, err := FormatWithOptions(got, tt.opts)
+			if err != nil {
+				t.Fatalf("FormatWithOptions error: %v", err)
+			}
+			if diff := cmp.Diff(string(got), string(got2)); diff != "" {
+				t.Errorf("FormatWithOptions not idempotent (-first +second):\n%s", diff)
+			}
+		})
+	}
+}
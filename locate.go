@@ -0,0 +1,139 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"sort"
+	"strconv"
+)
+
+// PathTo returns the JSON pointer (see RFC 6901) to target,
+// which must be v itself or a value within v, such as one returned by
+// Find or Query. It reports false if target is not within v,
+// or if target is within an object member that shares its name with
+// a preceding member, since no pointer resolves to such a value.
+func (v *Value) PathTo(target *Value) (string, bool) {
+	if p, ok := v.pathTo(Pointer{}, target); ok {
+		return p.String(), true
+	}
+	return "", false
+}
+
+func (v *Value) pathTo(p Pointer, target *Value) (Pointer, bool) {
+	if v == target {
+		return p, true
+	}
+	switch comp := v.Value.(type) {
+	case *Object:
+		for i := range comp.Members {
+			name := comp.Members[i].Name.Value.(Literal).memberName()
+			if p, ok := comp.Members[i].Value.pathTo(p.Append(name), target); ok {
+				if memberIndex(comp, name) != i {
+					return Pointer{}, false // shadowed by a duplicate name
+				}
+				return p, true
+			}
+		}
+	case *Array:
+		for i := range comp.Elements {
+			if p, ok := comp.Elements[i].pathTo(p.Append(strconv.Itoa(i)), target); ok {
+				return p, true
+			}
+		}
+	}
+	return Pointer{}, false
+}
+
+// Region is the part of a HuJSON value that a byte offset falls within.
+type Region int
+
+const (
+	// ValueRegion is the value itself, including the punctuation
+	// (e.g., braces, brackets, colons, and commas) of an object or array.
+	ValueRegion Region = iota
+	// NameRegion is the name of an object member.
+	NameRegion
+	// ExtraRegion is the whitespace and comments surrounding a value
+	// or member name, or that precede the closing brace or bracket
+	// of an object or array.
+	ExtraRegion
+)
+
+// Location is the result of locating a byte offset within a value.
+type Location struct {
+	// Node is the innermost value containing the offset.
+	// For an offset within an object member name or its surrounding Extra,
+	// this is the value of that member.
+	Node *Value
+	// Pointer is the JSON pointer to Node.
+	Pointer string
+	// Region is the part of Node that the offset falls within.
+	Region Region
+}
+
+// NodeAt returns the innermost value that contains the byte offset
+// along with the JSON pointer to it. It returns a nil node if the offset
+// is outside of v. See Locate for the interpretation of offsets.
+func (v *Value) NodeAt(offset int) (node *Value, pointer string) {
+	loc := v.Locate(offset)
+	return loc.Node, loc.Pointer
+}
+
+// Locate returns the location of the byte offset within v.
+// The offset is interpreted relative to the StartOffset and EndOffset
+// of each value, which are populated by Parse and UpdateOffsets.
+// Call UpdateOffsets beforehand if v has been modified since then.
+// It returns the zero Location if the offset is outside of v.
+func (v *Value) Locate(offset int) Location {
+	if offset < v.StartOffset-len(v.BeforeExtra) || v.EndOffset+len(v.AfterExtra) <= offset {
+		return Location{}
+	}
+	return v.locate(Pointer{}, offset)
+}
+
+// locate locates the offset, which must be within v or its surrounding Extra.
+func (v *Value) locate(p Pointer, offset int) Location {
+	if offset < v.StartOffset || v.EndOffset <= offset {
+		return Location{v, p.String(), ExtraRegion}
+	}
+	switch comp := v.Value.(type) {
+	case *Object:
+		// Members are ordered by offset, so search for the first member
+		// that ends after the offset.
+		i := sort.Search(len(comp.Members), func(i int) bool {
+			val := &comp.Members[i].Value
+			return offset < val.EndOffset+len(val.AfterExtra)
+		})
+		if i < len(comp.Members) {
+			name, val := &comp.Members[i].Name, &comp.Members[i].Value
+			p := p.Append(name.Value.(Literal).memberName())
+			switch {
+			case name.StartOffset <= offset && offset < name.EndOffset:
+				return Location{val, p.String(), NameRegion}
+			case name.StartOffset-len(name.BeforeExtra) <= offset && offset < name.EndOffset+len(name.AfterExtra):
+				return Location{val, p.String(), ExtraRegion}
+			case val.StartOffset-len(val.BeforeExtra) <= offset:
+				return val.locate(p, offset)
+			}
+		}
+	case *Array:
+		i := sort.Search(len(comp.Elements), func(i int) bool {
+			val := &comp.Elements[i]
+			return offset < val.EndOffset+len(val.AfterExtra)
+		})
+		if i < len(comp.Elements) {
+			if val := &comp.Elements[i]; val.StartOffset-len(val.BeforeExtra) <= offset {
+				return val.locate(p.Append(strconv.Itoa(i)), offset)
+			}
+		}
+	}
+	if comp, ok := v.Value.(composite); ok {
+		afterExtra := *comp.afterExtra()
+		if end := v.EndOffset - len("}"); end-len(afterExtra) <= offset && offset < end {
+			return Location{v, p.String(), ExtraRegion}
+		}
+	}
+	return Location{v, p.String(), ValueRegion}
+}
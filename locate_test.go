@@ -0,0 +1,114 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPathTo(t *testing.T) {
+	v, err := Parse([]byte(`{"a/b": [0, {"~": "x"}], unquoted: {}}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	for _, ptr := range []string{"", "/a~1b", "/a~1b/0", "/a~1b/1", "/a~1b/1/~0", "/unquoted"} {
+		got, ok := v.PathTo(v.Find(ptr))
+		if !ok || got != ptr {
+			t.Errorf("PathTo(Find(%q)) = (%q, %v), want (%q, true)", ptr, got, ok, ptr)
+		}
+	}
+	other := Value{Value: Literal("null")}
+	if got, ok := v.PathTo(&other); ok {
+		t.Errorf("PathTo(other) = (%q, true), want false", got)
+	}
+	if got, ok := v.PathTo(nil); ok {
+		t.Errorf("PathTo(nil) = (%q, true), want false", got)
+	}
+
+	dupe, err := Parse([]byte(`{"a": {"b": 1}, "a": {"b": 2}}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	obj := dupe.Value.(*Object)
+	if got, ok := dupe.PathTo(&obj.Members[0].Value.Value.(*Object).Members[0].Value); !ok || got != "/a/b" {
+		t.Errorf("PathTo(first duplicate) = (%q, %v), want (%q, true)", got, ok, "/a/b")
+	}
+	if got, ok := dupe.PathTo(&obj.Members[1].Value.Value.(*Object).Members[0].Value); ok {
+		t.Errorf("PathTo(second duplicate) = (%q, true), want false", got)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	const in = `// Header
+{
+	/* Name */ "name" /* Colon */ : /* Value */ "value" /* Comma */ ,
+	"list": [1, /* Two */ 22, [333]],
+	unquoted: null,
+	// End of object
+} // Trailer
+`
+	v, err := Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	tests := []struct {
+		substr  string // locate the first byte of the first occurrence
+		pointer string
+		region  Region
+	}{
+		{"// Header", "", ExtraRegion},
+		{"{", "", ValueRegion},
+		{"/* Name */", "/name", ExtraRegion},
+		{`"name"`, "/name", NameRegion},
+		{`me" /* Colon`, "/name", NameRegion},
+		{"/* Colon */", "/name", ExtraRegion},
+		{": /* Value", "", ValueRegion},
+		{"/* Value */", "/name", ExtraRegion},
+		{`"value"`, "/name", ValueRegion},
+		{"/* Comma */", "/name", ExtraRegion},
+		{",\n\t\"list\"", "", ValueRegion},
+		{`"list"`, "/list", NameRegion},
+		{"[1", "/list", ValueRegion},
+		{"1,", "/list/0", ValueRegion},
+		{"/* Two */", "/list/1", ExtraRegion},
+		{"22", "/list/1", ValueRegion},
+		{"2,", "/list/1", ValueRegion},
+		{"[333", "/list/2", ValueRegion},
+		{"333", "/list/2/0", ValueRegion},
+		{"]],", "/list/2", ValueRegion},
+		{"unquoted", "/unquoted", NameRegion},
+		{"null", "/unquoted", ValueRegion},
+		{"// End", "", ExtraRegion},
+		{"} //", "", ValueRegion},
+		{"// Trailer", "", ExtraRegion},
+	}
+	for _, tt := range tests {
+		offset := strings.Index(in, tt.substr)
+		loc := v.Locate(offset)
+		if loc.Pointer != tt.pointer || loc.Region != tt.region {
+			t.Errorf("Locate(%d) at %q = (%q, %v), want (%q, %v)", offset, tt.substr, loc.Pointer, loc.Region, tt.pointer, tt.region)
+		}
+		if loc.Node != v.Find(tt.pointer) {
+			t.Errorf("Locate(%d) at %q returned node not at %q", offset, tt.substr, tt.pointer)
+		}
+		node, ptr := v.NodeAt(offset)
+		if node != loc.Node || ptr != loc.Pointer {
+			t.Errorf("NodeAt(%d) = (%p, %q), want (%p, %q)", offset, node, ptr, loc.Node, loc.Pointer)
+		}
+	}
+
+	// Every offset within the input must be locatable.
+	for i := 0; i < len(in); i++ {
+		if loc := v.Locate(i); loc.Node == nil {
+			t.Errorf("Locate(%d) = nil, want non-nil", i)
+		}
+	}
+	for _, offset := range []int{-1, len(in)} {
+		if node, ptr := v.NodeAt(offset); node != nil || ptr != "" {
+			t.Errorf("NodeAt(%d) = (%p, %q), want (nil, \"\")", offset, node, ptr)
+		}
+	}
+}
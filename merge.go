@@ -0,0 +1,234 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// StrategicMerge merges overlay into the value, similar to a JSON merge patch
+// (RFC 7386), but with support for merging arrays of objects by a key member,
+// similar to a Kubernetes strategic merge patch.
+//
+// Objects are merged member by member: an overlay member with a null value
+// removes the member with the same name, a member present in both is merged
+// recursively, and any other overlay member is appended to the object.
+// As with RFC 7386, null members are omitted from overlay objects that are
+// appended or that replace a value that is not an object.
+//
+// Arrays are replaced wholesale, unless the JSON pointer to the array matches
+// a pattern in keys, which maps the pattern to the name of the member that
+// identifies each element (e.g., {"/services": "name"}). In that case, each
+// overlay element is merged into the element with an equal key member,
+// or appended to the array if no such element exists.
+// A pattern is a JSON pointer where a "*" reference token matches any
+// member name or array index (e.g., "/hosts/*/ports").
+// If multiple patterns match, the one with the fewest wildcards is used.
+//
+// All other overlay values replace the corresponding value.
+//
+// Comments in the value are preserved, and comments associated with
+// overlay members and elements are carried over. When both have leading
+// comments, the overlay comments are appended to those in the value.
+// When both have trailing comments, those in the value are kept.
+//
+// It reports an error if an overlay element of a keyed array is not an object
+// with the key member, in which case the value is left partially mutated.
+// Use Clone to preserve the original value.
+// It does not format the value. It is recommended that Format be called after.
+func (v *Value) StrategicMerge(overlay Value, keys map[string]string) error {
+	patterns := make([]keyPattern, 0, len(keys))
+	for pattern, key := range keys {
+		p, err := parsePointer(pattern)
+		if err != nil {
+			return fmt.Errorf("hujson: invalid pointer pattern %q", pattern)
+		}
+		patterns = append(patterns, keyPattern{p, key})
+	}
+	// Prefer patterns with fewer wildcards, breaking ties deterministically.
+	sort.Slice(patterns, func(i, j int) bool {
+		ni, nj := patterns[i].pattern.wildcards(), patterns[j].pattern.wildcards()
+		return ni < nj || (ni == nj && patterns[i].pattern.String() < patterns[j].pattern.String())
+	})
+	return v.strategicMerge(Pointer{}, overlay, patterns)
+}
+
+// keyPattern is a pointer pattern in the keys of StrategicMerge
+// along with the name of the member that identifies array elements.
+type keyPattern struct {
+	pattern Pointer
+	key     string
+}
+
+func (v *Value) strategicMerge(ptr Pointer, overlay Value, keys []keyPattern) error {
+	switch ov := overlay.Value.(type) {
+	case *Object:
+		obj, ok := v.Value.(*Object)
+		if !ok {
+			break
+		}
+		for j, m := range ov.Members {
+			name := m.Name.Value.(Literal).memberName()
+			i := memberIndex(obj, name)
+			switch {
+			case m.Value.Value.Kind() == 'n':
+				if i >= 0 {
+					removeAt(obj, i)
+				}
+			case i >= 0:
+				if err := obj.Members[i].Value.strategicMerge(ptr.Append(name), m.Value, keys); err != nil {
+					return err
+				}
+				mergeComments(obj, i, ov, j)
+			default:
+				m2 := copyAt(ov, j)
+				m2.removeNullMembers()
+				insertAt(obj, obj.length(), m2)
+				obj.Members[obj.length()-1].Name.Value = m.Name.Value.clone()
+			}
+		}
+		return nil
+	case *Array:
+		arr, ok := v.Value.(*Array)
+		key, keyed := matchKeyPatterns(keys, ptr)
+		if !ok || !keyed {
+			break
+		}
+		for j, e := range ov.Elements {
+			id := elementKey(e, key)
+			if id == nil {
+				return fmt.Errorf("hujson: strategic merge: element %d at %q lacks member %q", j, ptr.String(), key)
+			}
+			i := -1
+			for k, e2 := range arr.Elements {
+				if id2 := elementKey(e2, key); id2 != nil && equalValue(*id2, *id) {
+					i = k
+					break
+				}
+			}
+			if i < 0 {
+				e2 := copyAt(ov, j)
+				e2.removeNullMembers()
+				insertAt(arr, arr.length(), e2)
+				continue
+			}
+			if err := arr.Elements[i].strategicMerge(ptr.Append(strconv.Itoa(i)), e, keys); err != nil {
+				return err
+			}
+			mergeComments(arr, i, ov, j)
+		}
+		return nil
+	}
+	v.Value = overlay.Value.clone()
+	v.removeNullMembers()
+	return nil
+}
+
+// removeNullMembers recursively removes object members with a null value,
+// as a merge patch does when applied to a value that is not an object.
+// Arrays are not descended into since they are replaced wholesale.
+func (v *Value) removeNullMembers() {
+	if obj, ok := v.Value.(*Object); ok {
+		for i := obj.length() - 1; i >= 0; i-- {
+			if obj.Members[i].Value.Value.Kind() == 'n' {
+				removeAt(obj, i)
+			} else {
+				obj.Members[i].Value.removeNullMembers()
+			}
+		}
+	}
+}
+
+// memberIndex returns the index of the first member in obj with the given name,
+// or -1 if there is no such member.
+func memberIndex(obj *Object, name string) int {
+	for i, m := range obj.Members {
+		if m.Name.Value.(Literal).equalString(name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// elementKey returns the value of the named member of an object element,
+// or nil if v is not an object or has no such member.
+func elementKey(v Value, name string) *Value {
+	if obj, ok := v.Value.(*Object); ok {
+		if i := memberIndex(obj, name); i >= 0 {
+			return &obj.Members[i].Value
+		}
+	}
+	return nil
+}
+
+// mergeComments merges the comments associated with the j-th member or
+// element in src into those associated with the i-th member or element in dst.
+func mergeComments(dst composite, i int, src composite, j int) {
+	if leading := src.beforeExtraAt(j + 0).extractLeadingComments(true); leading.hasComment() {
+		b := dst.beforeExtraAt(i + 0)
+		existing := b.extractLeadingComments(true)
+		leading = bytes.TrimSpace(leading)
+		if !bytes.Contains(existing, leading) {
+			// Place the new comments on their own line after any existing
+			// comments while preserving the indentation of the value.
+			var indent []byte
+			if k := bytes.LastIndexByte(*b, '\n'); k >= 0 && consumeWhitespace((*b)[k:]) == len(*b)-k {
+				indent = (*b)[k+len("\n"):]
+			}
+			var merged Extra
+			if existing.hasComment() {
+				merged = append(merged, bytes.TrimRight(existing, " \t\r\n")...)
+				merged = append(append(merged, '\n'), indent...)
+			}
+			merged = append(merged, leading...)
+			merged = append(append(merged, '\n'), indent...)
+			b.injectLeadingComments(merged)
+		}
+	}
+	if trailing := src.beforeExtraAt(j + 1).extractTrailingcomments(true); trailing.hasComment() {
+		b := dst.beforeExtraAt(i + 1)
+		if !b.extractTrailingcomments(true).hasComment() {
+			b.injectTrailingComments(trailing)
+		}
+	}
+}
+
+// matchKeyPatterns returns the key of the first pattern in keys that
+// matches ptr, where keys are sorted by the number of wildcards.
+func matchKeyPatterns(keys []keyPattern, ptr Pointer) (string, bool) {
+	for _, k := range keys {
+		if matchPointerPattern(k.pattern, ptr) {
+			return k.key, true
+		}
+	}
+	return "", false
+}
+
+// matchPointerPattern reports whether ptr matches the pattern,
+// where a "*" reference token in the pattern matches any reference token.
+func matchPointerPattern(pattern, ptr Pointer) bool {
+	if len(pattern.tokens) != len(ptr.tokens) {
+		return false
+	}
+	for i, token := range pattern.tokens {
+		if token != "*" && token != ptr.tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// wildcards returns the number of "*" reference tokens in p.
+func (p Pointer) wildcards() (n int) {
+	for _, token := range p.tokens {
+		if token == "*" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Conflict is a value that was changed in incompatible ways by both sides
+// of a three-way merge.
+type Conflict struct {
+	// Pointer is the JSON pointer to the conflicting value.
+	Pointer string
+	// Base, Ours, and Theirs are the conflicting value in each input,
+	// where nil indicates that the value is absent from that input
+	// (e.g., because it was deleted on one side and modified on the other).
+	Base, Ours, Theirs *Value
+}
+
+// Merge3 performs a three-way merge of ours and theirs,
+// which are both derived from the common ancestor base.
+// Changes are identified by comparing values semantically,
+// such that formatting changes alone do not cause conflicts.
+//
+// Objects are merged member by member, so edits to different members
+// never conflict. Arrays of the same length are merged element by element.
+// Elements appended to an array on both sides are all kept.
+// All other values are taken from whichever side changed them.
+// A value changed differently on both sides is a conflict,
+// in which case the merged result holds the value from ours, or the value
+// from theirs if it was deleted in ours and modified in theirs.
+//
+// The result is based on ours, preserving all of its comments.
+// Values taken from theirs bring along their comments.
+// Leading and trailing comments of object members and array elements
+// that were only changed in theirs are also taken from theirs.
+// The inputs are not mutated.
+//
+// It does not format the result. It is recommended that Format be called after.
+func Merge3(base, ours, theirs Value) (Value, []Conflict) {
+	m := merger{}
+	result := cloneWithCommas(ours)
+	m.merge(&result, "", &base, &ours, &theirs)
+	if bytes.Equal(base.BeforeExtra, ours.BeforeExtra) {
+		result.BeforeExtra = copyBytes(theirs.BeforeExtra)
+	}
+	if bytes.Equal(base.AfterExtra, ours.AfterExtra) {
+		result.AfterExtra = copyBytes(theirs.AfterExtra)
+	}
+	return result, m.conflicts
+}
+
+// cloneWithCommas is like Clone, but also preserves trailing commas,
+// which are dropped by Clone if there is no whitespace or comment
+// between the trailing comma and the closing bracket.
+func cloneWithCommas(v Value) Value {
+	var commas []bool
+	v.Range(func(v *Value) bool {
+		if comp, ok := v.Value.(composite); ok {
+			commas = append(commas, hasTrailingComma(comp))
+		}
+		return true
+	})
+	v2 := v.Clone()
+	v2.Range(func(v *Value) bool {
+		if comp, ok := v.Value.(composite); ok {
+			setTrailingComma(comp, commas[0])
+			commas = commas[1:]
+		}
+		return true
+	})
+	return v2
+}
+
+type merger struct {
+	conflicts []Conflict
+}
+
+func (m *merger) conflict(ptr string, base, ours, theirs *Value) {
+	m.conflicts = append(m.conflicts, Conflict{ptr, base, ours, theirs})
+}
+
+// merge merges changes from base to theirs into dst,
+// which initially holds a copy of ours.
+func (m *merger) merge(dst *Value, ptr string, base, ours, theirs *Value) {
+	bObj, bOk := base.Value.(*Object)
+	oObj, oOk := ours.Value.(*Object)
+	tObj, tOk := theirs.Value.(*Object)
+	if bOk && oOk && tOk {
+		m.mergeObjects(dst.Value.(*Object), ptr, bObj, oObj, tObj)
+		return
+	}
+
+	bArr, bOk := base.Value.(*Array)
+	oArr, oOk := ours.Value.(*Array)
+	tArr, tOk := theirs.Value.(*Array)
+	if bOk && oOk && tOk && bArr.length() == oArr.length() && bArr.length() == tArr.length() {
+		dstArr := dst.Value.(*Array)
+		for i := range bArr.Elements {
+			m.merge(&dstArr.Elements[i], ptr+"/"+strconv.Itoa(i), &bArr.Elements[i], &oArr.Elements[i], &tArr.Elements[i])
+			mergeComments3(dstArr, i, bArr, i, oArr, i, tArr, i)
+		}
+		return
+	}
+
+	switch {
+	case equalValue(*ours, *theirs), equalValue(*base, *theirs):
+		// Keep ours.
+	case equalValue(*base, *ours):
+		dst.Value = theirs.Value.clone()
+	case bOk && oOk && tOk && isPrefix(bArr, oArr) && isPrefix(bArr, tArr):
+		// Both sides only appended elements, so append those from theirs
+		// that ours did not also append.
+		dstArr := dst.Value.(*Array)
+		oursAppended := oArr.Elements[bArr.length():]
+	appendLoop:
+		for j := bArr.length(); j < tArr.length(); j++ {
+			for _, e := range oursAppended {
+				if equalValue(e, tArr.Elements[j]) {
+					continue appendLoop
+				}
+			}
+			insertAt(dstArr, dstArr.length(), copyAt(tArr, j))
+		}
+	default:
+		m.conflict(ptr, base, ours, theirs)
+	}
+}
+
+func (m *merger) mergeObjects(dst *Object, ptr string, base, ours, theirs *Object) {
+	// Handle members deleted in theirs.
+	for bi, bm := range base.Members {
+		name := bm.Name.Value.(Literal).memberName()
+		if memberIndex(base, name) != bi || memberIndex(theirs, name) >= 0 {
+			continue // duplicate name or not deleted in theirs
+		}
+		oi := memberIndex(ours, name)
+		switch {
+		case oi < 0:
+			// Deleted on both sides.
+		case equalValue(base.Members[bi].Value, ours.Members[oi].Value):
+			removePreservingComma(dst, memberIndex(dst, name))
+		default:
+			m.conflict(ptr+"/"+escapePointerToken(name), &base.Members[bi].Value, &ours.Members[oi].Value, nil)
+		}
+	}
+
+	// Handle members added or modified in theirs.
+	for ti, tm := range theirs.Members {
+		name := tm.Name.Value.(Literal).memberName()
+		if memberIndex(theirs, name) != ti {
+			continue // duplicate name
+		}
+		memberPtr := ptr + "/" + escapePointerToken(name)
+		bi, oi, di := memberIndex(base, name), memberIndex(ours, name), memberIndex(dst, name)
+		switch {
+		case bi < 0 && oi < 0:
+			// Added only in theirs.
+			insertMemberAfter(dst, base, theirs, ti)
+		case bi < 0:
+			// Added on both sides.
+			_, oOk := ours.Members[oi].Value.Value.(*Object)
+			_, tOk := tm.Value.Value.(*Object)
+			switch {
+			case oOk && tOk:
+				empty := Value{Value: new(Object)}
+				m.merge(&dst.Members[di].Value, memberPtr, &empty, &ours.Members[oi].Value, &theirs.Members[ti].Value)
+			case !equalValue(ours.Members[oi].Value, tm.Value):
+				m.conflict(memberPtr, nil, &ours.Members[oi].Value, &theirs.Members[ti].Value)
+			}
+		case oi < 0:
+			// Deleted in ours.
+			if !equalValue(base.Members[bi].Value, tm.Value) {
+				insertMemberAfter(dst, base, theirs, ti)
+				m.conflict(memberPtr, &base.Members[bi].Value, nil, &theirs.Members[ti].Value)
+			}
+		default:
+			m.merge(&dst.Members[di].Value, memberPtr, &base.Members[bi].Value, &ours.Members[oi].Value, &theirs.Members[ti].Value)
+			mergeComments3(dst, di, base, bi, ours, oi, theirs, ti)
+		}
+	}
+}
+
+// insertMemberAfter copies the i-th member of theirs into dst,
+// placing it after the member that precedes it in theirs if present in dst,
+// otherwise at the end of dst. Members that were added only in ours
+// immediately after that position are kept before the inserted member.
+func insertMemberAfter(dst, base, theirs *Object, i int) {
+	at := dst.length()
+	if i == 0 {
+		at = 0
+	} else if j := memberIndex(dst, theirs.Members[i-1].Name.Value.(Literal).memberName()); j >= 0 {
+		at = j + 1
+	}
+	for at < dst.length() {
+		name := dst.Members[at].Name.Value.(Literal).memberName()
+		if memberIndex(base, name) >= 0 || memberIndex(theirs, name) >= 0 {
+			break
+		}
+		at++
+	}
+	insertPreservingComma(dst, at, copyAt(theirs, i))
+	dst.Members[at].Name.Value = theirs.Members[i].Name.Value.clone()
+}
+
+// mergeComments3 updates the leading and trailing comments of the member or
+// element at dst[di] with those from theirs if they were only changed in theirs.
+func mergeComments3(dst composite, di int, base composite, bi int, ours composite, oi int, theirs composite, ti int) {
+	bLeading := base.beforeExtraAt(bi + 0).extractLeadingComments(true)
+	oLeading := ours.beforeExtraAt(oi + 0).extractLeadingComments(true)
+	tLeading := theirs.beforeExtraAt(ti + 0).extractLeadingComments(true)
+	if equalComments(bLeading, oLeading) && !equalComments(bLeading, tLeading) {
+		dst.beforeExtraAt(di + 0).extractLeadingComments(false)
+		dst.beforeExtraAt(di + 0).injectLeadingComments(tLeading)
+	}
+	bTrailing := base.beforeExtraAt(bi + 1).extractTrailingcomments(true)
+	oTrailing := ours.beforeExtraAt(oi + 1).extractTrailingcomments(true)
+	tTrailing := theirs.beforeExtraAt(ti + 1).extractTrailingcomments(true)
+	if equalComments(bTrailing, oTrailing) && !equalComments(bTrailing, tTrailing) {
+		dst.beforeExtraAt(di + 1).extractTrailingcomments(false)
+		dst.beforeExtraAt(di + 1).injectTrailingComments(tTrailing)
+	}
+}
+
+// equalComments reports whether x and y have the same comments,
+// ignoring surrounding whitespace.
+func equalComments(x, y Extra) bool {
+	return bytes.Equal(bytes.TrimSpace(x), bytes.TrimSpace(y))
+}
+
+// isPrefix reports whether the elements of prefix are semantically equal
+// to the leading elements of arr.
+func isPrefix(prefix, arr *Array) bool {
+	if prefix.length() > arr.length() {
+		return false
+	}
+	for i := range prefix.Elements {
+		if !equalValue(prefix.Elements[i], arr.Elements[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,369 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testdataStrategicMerge = []struct {
+	in      string
+	overlay string
+	keys    map[string]string
+	want    string
+	wantErr error
+}{{
+	in:      `{"a": 1, "b": {"c": 2, "d": 3}}`,
+	overlay: `{"b": {"c": null, "e": 4}, "f": 5}`,
+	want:    `{"a": 1, "b": {"d": 3, "e": 4}, "f": 5}`,
+}, {
+	in:      `{"a": [1, 2, 3]}`,
+	overlay: `{"a": [4]}`,
+	want:    `{"a": [4]}`,
+}, {
+	in:      `{"a": {"b": 1}}`,
+	overlay: `{"a": "replaced"}`,
+	want:    `{"a": "replaced"}`,
+}, {
+	in:      `{"a": "scalar", "b": 1}`,
+	overlay: `{"a": {"x": 1, "y": null, "z": {"w": null}}, "c": {"z": null, "l": [null, {"n": null}]}}`,
+	want:    `{"a": {"x": 1, "z": {}}, "b": 1, "c": {"l": [null, {"n": null}]}}`,
+}, {
+	in:      `{"list": [{"name": "a", "x": 1}]}`,
+	overlay: `{"list": [{"name": "a", "x": null}, {"name": "b", "y": null}]}`,
+	keys:    map[string]string{"/list": "name"},
+	want:    `{"list": [{"name": "a"}, {"name": "b"}]}`,
+}, {
+	in: `{
+	"services": [
+		// The web server.
+		{"name": "web", "port": 80},
+		{"name": "db", "port": 5432}, // The database.
+	],
+}`,
+	overlay: `{
+	"services": [
+		{"name": "db", "port": 5433},
+		// The cache.
+		{"name": "cache", "port": 6379},
+	],
+}`,
+	keys: map[string]string{"/services": "name"},
+	want: `{
+	"services": [
+		// The web server.
+		{"name": "web", "port": 80},
+		{"name": "db", "port": 5433}, // The database.
+		// The cache.
+		{"name": "cache", "port": 6379},
+	],
+}`,
+}, {
+	in: `{
+	"hosts": {
+		"alpha": {"ports": [{"port": 80, "proto": "tcp"}]},
+		"beta": {"ports": [{"port": 53, "proto": "udp"}]},
+	},
+}`,
+	overlay: `{
+	"hosts": {
+		"alpha": {"ports": [{"port": 443, "proto": "tcp"}]},
+		"beta": {"ports": [{"port": 53, "proto": "tcp"}]},
+	},
+}`,
+	keys: map[string]string{"/hosts/*/ports": "port"},
+	want: `{
+	"hosts": {
+		"alpha": {"ports": [{"port": 80, "proto": "tcp"}, {"port": 443, "proto": "tcp"}]},
+		"beta": {"ports": [{"port": 53, "proto": "tcp"}]},
+	},
+}`,
+}, {
+	in: `{
+	// Comment1
+	"a": 1, // Comment2
+	"b": 2,
+}`,
+	overlay: `{
+	// Comment3
+	"a": 3, // Comment4
+	// Comment5
+	"b": 4, // Comment6
+	// Comment7
+	"c": 5, // Comment8
+}`,
+	want: `{
+	// Comment1
+	// Comment3
+	"a": 3, // Comment2
+	// Comment5
+	"b": 4, // Comment6
+	// Comment7
+	"c": 5, // Comment8
+}`,
+}, {
+	in:      `{"list": [{"name": "a"}]}`,
+	overlay: `{"list": [{"id": "a"}]}`,
+	keys:    map[string]string{"/list": "name"},
+	wantErr: errors.New(`hujson: strategic merge: element 0 at "/list" lacks member "name"`),
+}, {
+	in:      `{}`,
+	overlay: `{}`,
+	keys:    map[string]string{"list": "name"},
+	wantErr: errors.New(`hujson: invalid pointer pattern "list"`),
+}}
+
+func TestStrategicMerge(t *testing.T) {
+	for _, tt := range testdataStrategicMerge {
+		t.Run("", func(t *testing.T) {
+			v, err := Parse([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			overlay, err := Parse([]byte(tt.overlay))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			gotErr := v.StrategicMerge(overlay, tt.keys)
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("StrategicMerge error mismatch:\ngot  %v\nwant %v", gotErr, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			v.Format()
+			got := v.String()
+			want, err := Format([]byte(tt.want))
+			if err != nil {
+				t.Fatalf("Format error: %v", err)
+			}
+			if diff := cmp.Diff(string(want), got); diff != "" {
+				t.Errorf("StrategicMerge mismatch (-want +got):\n%s", diff)
+			}
+			if got := overlay.String(); got != tt.overlay {
+				t.Errorf("StrategicMerge mutated overlay:\ngot  %s\nwant %s", got, tt.overlay)
+			}
+		})
+	}
+}
+
+func TestMatchPointerPattern(t *testing.T) {
+	tests := []struct {
+		pattern, ptr string
+		want         bool
+	}{
+		{"", "", true},
+		{"", "/a", false},
+		{"/a", "/a", true},
+		{"/a", "/a/b", false},
+		{"/*", "/a", true},
+		{"/*", "", false},
+		{"/a/*/c", "/a/0/c", true},
+		{"/a/*/c", "/a/b/d", false},
+		{"/a~1b", "/a~1b", true},
+		{"/", "/", true},
+	}
+	for _, tt := range tests {
+		pattern, err := ParsePointer(tt.pattern)
+		if err != nil {
+			t.Fatalf("ParsePointer error: %v", err)
+		}
+		ptr, err := ParsePointer(tt.ptr)
+		if err != nil {
+			t.Fatalf("ParsePointer error: %v", err)
+		}
+		if got := matchPointerPattern(pattern, ptr); got != tt.want {
+			t.Errorf("matchPointerPattern(%q, %q) = %v, want %v", tt.pattern, tt.ptr, got, tt.want)
+		}
+	}
+}
+
+var testdataMerge3 = []struct {
+	base, ours, theirs string
+	want               string
+	wantConflicts      []string
+}{{
+	base:   "{\n\t\"a\": 1,\n\t\"b\": 2,\n\t\"c\": [1, 2,],\n}",
+	ours:   "{\n\t\"a\": 10,\n\t\"b\": 2,\n\t\"c\": [1, 2,],\n}",
+	theirs: "{\n\t\"a\": 1,\n\t\"c\": [1, 2,],\n\t\"d\": 4,\n}",
+	want:   "{\n\t\"a\": 10,\n\t\"c\": [1, 2,],\n\t\"d\": 4,\n}",
+}, {
+	base:   `{"a": 1, "b": 2}`,
+	ours:   `{"a": 10, "b": 2}`,
+	theirs: `{"a": 1, "b": 20}`,
+	want:   `{"a": 10, "b": 20}`,
+}, {
+	base:   `{"a": 1, "b": 2, "c": 3}`,
+	ours:   `{"a": 1, "b": 2, "c": 3, "d": 4}`,
+	theirs: `{"a": 1, "c": 3, "e": 5}`,
+	want:   `{"a": 1, "c": 3, "d": 4, "e": 5}`,
+}, {
+	base:   `{"a": 1}`,
+	ours:   `{"z": 0, "a": 1}`,
+	theirs: `{"y": 0, "a": 1, "b": 2}`,
+	want:   `{"z": 0, "y": 0, "a": 1, "b": 2}`,
+}, {
+	base:          `{"a": 1, "b": 2}`,
+	ours:          `{"a": 10, "b": 2}`,
+	theirs:        `{"a": 20, "b": 3}`,
+	want:          `{"a": 10, "b": 3}`,
+	wantConflicts: []string{"/a"},
+}, {
+	base:          `{"a": {"b": 1}, "c": 1}`,
+	ours:          `{"c": 1}`,
+	theirs:        `{"a": {"b": 2}, "c": 1}`,
+	want:          `{"a": {"b": 2}, "c": 1}`,
+	wantConflicts: []string{"/a"},
+}, {
+	base:          `{"a": 1}`,
+	ours:          `{"a": 2}`,
+	theirs:        `{}`,
+	want:          `{"a": 2}`,
+	wantConflicts: []string{"/a"},
+}, {
+	base:   `{}`,
+	ours:   `{"a": {"x": 1}, "b": 1}`,
+	theirs: `{"a": {"y": 2}, "b": 1}`,
+	want:   `{"a": {"x": 1, "y": 2}, "b": 1}`,
+}, {
+	base:          `{}`,
+	ours:          `{"a/b": 1}`,
+	theirs:        `{"a/b": 2}`,
+	want:          `{"a/b": 1}`,
+	wantConflicts: []string{"/a~1b"},
+}, {
+	base:   `[1, {"a": 1}, 3]`,
+	ours:   `[1, {"a": 1, "b": 2}, 30]`,
+	theirs: `[10, {"a": 1, "c": 3}, 3]`,
+	want:   `[10, {"a": 1, "b": 2, "c": 3}, 30]`,
+}, {
+	base:   `{"list": [1, 2]}`,
+	ours:   `{"list": [1, 2, 3, 4]}`,
+	theirs: `{"list": [1, 2, 4, 5]}`,
+	want:   `{"list": [1, 2, 3, 4, 5]}`,
+}, {
+	base:   `{"list": [1, 2]}`,
+	ours:   `{"list": [1, 2]}`,
+	theirs: `{"list": [2]}`,
+	want:   `{"list": [2]}`,
+}, {
+	base:          `{"list": [1, 2]}`,
+	ours:          `{"list": [2, 1]}`,
+	theirs:        `{"list": [1, 2, 3]}`,
+	want:          `{"list": [2, 1]}`,
+	wantConflicts: []string{"/list"},
+}, {
+	base:          `1`,
+	ours:          `2`,
+	theirs:        `3`,
+	want:          `2`,
+	wantConflicts: []string{""},
+}, {
+	base: `{
+	"a": 1,
+	"b": 2,
+}`,
+	ours: `{
+	// Comment from ours.
+	"a": 1,
+	"b": 2,
+}`,
+	theirs: `{
+	"a":1,"b":3, // Comment from theirs.
+}`,
+	want: `{
+	// Comment from ours.
+	"a": 1,
+	"b": 3, // Comment from theirs.
+}`,
+}, {
+	base: `{
+	// Base comment.
+	"a": 1,
+}`,
+	ours: `{
+	// Base comment.
+	"a": 1,
+}`,
+	theirs: `// Header.
+{
+	// Updated comment.
+	"a": 1,
+	// New member.
+	"b": [/* empty */],
+}`,
+	want: `// Header.
+{
+	// Updated comment.
+	"a": 1,
+	// New member.
+	"b": [/* empty */],
+}`,
+}, {
+	base: `{
+	// Base comment.
+	"a": 1,
+}`,
+	ours: `{
+	// Our comment.
+	"a": 1,
+}`,
+	theirs: `{
+	// Their comment.
+	"a": 1,
+}`,
+	want: `{
+	// Our comment.
+	"a": 1,
+}`,
+}}
+
+func TestMerge3(t *testing.T) {
+	for _, tt := range testdataMerge3 {
+		t.Run("", func(t *testing.T) {
+			base, err := Parse([]byte(tt.base))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			ours, err := Parse([]byte(tt.ours))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			theirs, err := Parse([]byte(tt.theirs))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			v, conflicts := Merge3(base, ours, theirs)
+			var gotConflicts []string
+			for _, c := range conflicts {
+				gotConflicts = append(gotConflicts, c.Pointer)
+			}
+			if diff := cmp.Diff(tt.wantConflicts, gotConflicts); diff != "" {
+				t.Errorf("Merge3 conflicts mismatch (-want +got):\n%s", diff)
+			}
+			v.Format()
+			got := v.String()
+			want, err := Format([]byte(tt.want))
+			if err != nil {
+				t.Fatalf("Format error: %v", err)
+			}
+			if diff := cmp.Diff(string(want), got); diff != "" {
+				t.Errorf("Merge3 mismatch (-want +got):\n%s", diff)
+			}
+			for _, in := range []struct {
+				v    Value
+				want string
+			}{{base, tt.base}, {ours, tt.ours}, {theirs, tt.theirs}} {
+				if got := in.v.String(); got != in.want {
+					t.Errorf("Merge3 mutated input:\ngot  %s\nwant %s", got, in.want)
+				}
+			}
+		})
+	}
+}
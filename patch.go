@@ -6,34 +6,14 @@ package hujson
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
-	"reflect"
 	"strings"
 )
 
-// TODO(dsnet): Insert/remove operations on an array has O(n) complexity
-// where n is the length of the array. We could improve this with more clever
-// data structure that has efficient insertion, deletion, and indexing.
-// One possibility is an "order statistic tree", which provide O(log n)
-// behavior for the necessary operations.
-// See https://en.wikipedia.org/wiki/Order_statistic_tree.
-
-// TODO(dsnet): Name lookup on an object has O(n) complexity performing
-// a linear search through all names. This can be alleviated by building
-// a map of names to indexes for relevant objects. Currently, we always insert
-// a new member at the end of the members list, so that operation carries an
-// amortized cost of O(1).
-
-// TODO(dsnet): Cache intermediate lookups when resolving a JSON pointer.
-// Patch operations tend to operate on paths that are related.
-// Caching can reduce pointer lookup from O(n) to be closer to O(1)
-// where n is the number of path segments in the JSON pointer.
-
 // TODO(dsnet): Batch sequential insert/remove operations performed
 // on the same object or array. This handles the possibly common case of batch
 // inserting or removing a number of consecutive members/elements.
-// Pointer caching may make this optimization unnecessary.
+// Pointer caching (see findCache) may make this optimization unnecessary.
 
 // Patch patches the value according to the provided patch file (per RFC 6902).
 // The patch file may be in the HuJSON format where comments around and within
@@ -44,37 +24,148 @@ import (
 // It does not format the value. It is recommended that Format be called after
 // applying a patch.
 func (v *Value) Patch(patch []byte) error {
-	ops, err := parsePatch(patch)
+	return v.PatchWithOptions(patch, PatchOptions{})
+}
+
+// PatchOptions configures the behavior of Value.PatchWithOptions.
+// The zero value only permits operations defined by RFC 6902.
+type PatchOptions struct {
+	// AllowCommentOps permits the "comment" and "uncomment" operations,
+	// which are a HuJSON-specific extension to RFC 6902.
+	//
+	// The "comment" operation replaces the comments associated with
+	// the value at "path" using the optional "leading" and "trailing" members:
+	//
+	//	{ "op": "comment", "path": "/acls/3", "leading": "// managed by X" }
+	//
+	// Each member must be a JSON string consisting only of HuJSON comments
+	// and whitespace, where a final line comment need not end with a newline.
+	// An empty string removes the existing comments, while an absent member
+	// leaves the existing comments untouched.
+	//
+	// The "uncomment" operation removes both the leading and trailing comments
+	// associated with the value at "path":
+	//
+	//	{ "op": "uncomment", "path": "/acls/3" }
+	//
+	// Comments are associated with a value using the same heuristics
+	// used to move comments when patching (see the comments in patch.go).
+	// The root value cannot be commented or uncommented.
+	AllowCommentOps bool
+
+	// AllowPatterns permits the "path" member of an operation to be
+	// a pattern that matches any number of values, where the operation
+	// is applied to each match. A pattern is a JSON pointer where
+	// a reference token may be "*" to match every member or element,
+	// or "[name=value]" to match every member or element that is an object
+	// with a member of the given name whose value is the given string
+	// or the given raw literal (e.g., "[id=3]" or "[enabled=true]"):
+	//
+	//	{ "op": "replace", "path": "/hosts/*/port", "value": 443 }
+	//	{ "op": "remove", "path": "/users/[name=bob]/role" }
+	//
+	// Any "/" or "~" within a name or value must be escaped as "~1" or "~0".
+	// The final reference token may be a regular token that does not exist
+	// (e.g., for an "add" operation).
+	//
+	// A pattern is expanded against the value as patched by prior operations.
+	// The matches are patched in reverse document order so that patching
+	// one match does not affect the location of the others.
+	// It is an error for a pattern to match no values or to be used
+	// as the path of a "move" operation. The "from" member is never a pattern.
+	AllowPatterns bool
+
+	// Applied, if non-nil, is called after an operation is applied
+	// with the index of the operation and the pointer to the value
+	// that it applied to. It is called for each match of a pattern.
+	// The value may be inspected, but not modified, within the call.
+	// Doing so requires the value to be brought up to date after each
+	// operation, which forgoes the optimizations for resizing large arrays.
+	Applied func(op int, pointer string)
+}
+
+// PatchWithOptions is like Patch, but permits extensions to RFC 6902
+// as specified by opts.
+func (v *Value) PatchWithOptions(patch []byte, opts PatchOptions) error {
+	ops, err := parsePatch(patch, opts)
 	if err != nil {
 		return err
 	}
+	c := new(findCache)
+	defer c.flush()
 	for i, op := range ops {
-		var err error
-		switch op.op {
-		case "add":
-			err = v.patchAdd(i, op)
-		case "remove", "replace":
-			err = v.patchRemoveOrReplace(i, op)
-		case "move", "copy":
-			err = v.patchMoveOrCopy(i, op)
-		case "test":
-			err = v.patchTest(i, op)
+		if opts.AllowPatterns {
+			if p, err := parsePointer(op.path); err == nil && p.isPattern() {
+				if err := v.patchPattern(c, i, op, p, opts); err != nil {
+					return err
+				}
+				continue
+			}
 		}
-		if err != nil {
+		if err := v.patchOperation(c, i, op); err != nil {
 			return err
 		}
+		if opts.Applied != nil {
+			c.flush() // allow the value to be inspected by Applied
+			opts.Applied(i, op.path)
+		}
+	}
+	return nil
+}
+
+// patchPattern applies op to every value matching the pattern.
+func (v *Value) patchPattern(c *findCache, i int, op patchOperation, pattern Pointer, opts PatchOptions) error {
+	if op.op == "move" {
+		return fmt.Errorf("hujson: patch operation %d: cannot move into pattern %q", i, op.path)
+	}
+	c.flush() // expandPattern operates without the cache
+	ptrs := v.expandPattern(pattern)
+	if len(ptrs) == 0 {
+		return fmt.Errorf("hujson: patch operation %d: no values match %q", i, op.path)
+	}
+	for j := len(ptrs) - 1; j >= 0; j-- {
+		op := op
+		op.path = ptrs[j].String()
+		if len(ptrs) > 1 && op.value.Value != nil {
+			op.value = op.value.Clone() // avoid aliasing across matches
+		}
+		if err := v.patchOperation(c, i, op); err != nil {
+			return err
+		}
+		if opts.Applied != nil {
+			c.flush() // allow the value to be inspected by Applied
+			opts.Applied(i, op.path)
+		}
+	}
+	return nil
+}
+
+func (v *Value) patchOperation(c *findCache, i int, op patchOperation) error {
+	switch op.op {
+	case "add":
+		return v.patchAdd(c, i, op)
+	case "remove", "replace":
+		return v.patchRemoveOrReplace(c, i, op)
+	case "move", "copy":
+		return v.patchMoveOrCopy(c, i, op)
+	case "test":
+		return v.patchTest(c, i, op)
+	case "comment", "uncomment":
+		return v.patchComment(c, i, op)
 	}
 	return nil
 }
 
 type patchOperation struct {
-	op    string // "add" | "remove" | "replace" | "move" | "copy" | "test"
-	path  string // used by all operations
-	from  string // used by "move" and "copy"
-	value Value  // used by "add", "replace", and "test"
+	op       string // "add" | "remove" | "replace" | "move" | "copy" | "test" | "comment" | "uncomment"
+	path     string // used by all operations
+	from     string // used by "move" and "copy"
+	value    Value  // used by "add", "replace", and "test"
+	leading  Extra  // used by "comment"; nil if absent
+	trailing Extra  // used by "comment"; nil if absent
 }
 
-func parsePatch(patch []byte) ([]patchOperation, error) {
+func parsePatch(patch []byte, opts PatchOptions) ([]patchOperation, error) {
 	v, err := Parse(patch)
 	if err != nil {
 		return nil, err
@@ -105,6 +196,11 @@ func parsePatch(patch []byte) ([]patchOperation, error) {
 				switch opType := m.Value.Value.(Literal).String(); opType {
 				case "add", "remove", "replace", "move", "copy", "test":
 					op.op = opType
+				case "comment", "uncomment":
+					if !opts.AllowCommentOps {
+						return nil, fmt.Errorf("hujson: patch operation %d: unknown operation %q", i, m.Value.Value)
+					}
+					op.op = opType
 				default:
 					return nil, fmt.Errorf("hujson: patch operation %d: unknown operation %q", i, m.Value.Value)
 				}
@@ -122,6 +218,22 @@ func parsePatch(patch []byte) ([]patchOperation, error) {
 				m.Value.BeforeExtra = obj.beforeExtraAt(j + 0).extractLeadingComments(true)
 				m.Value.AfterExtra = obj.beforeExtraAt(j + 1).extractTrailingcomments(true)
 				op.value = m.Value
+			case "leading", "trailing":
+				if !opts.AllowCommentOps {
+					break
+				}
+				if m.Value.Value.Kind() != '"' {
+					return nil, fmt.Errorf("hujson: patch operation %d: member %q must be a JSON string", i, name)
+				}
+				comments, ok := parseCommentMember(m.Value.Value.(Literal).String())
+				if !ok {
+					return nil, fmt.Errorf("hujson: patch operation %d: member %q must only contain comments", i, name)
+				}
+				if name == "leading" {
+					op.leading = comments
+				} else {
+					op.trailing = comments
+				}
 			}
 		}
 		switch {
@@ -133,19 +245,22 @@ func parsePatch(patch []byte) ([]patchOperation, error) {
 			return nil, fmt.Errorf("hujson: patch operation %d: missing required member %q", i, "from")
 		case !seen["value"] && (op.op == "add" || op.op == "replace" || op.op == "test"):
 			return nil, fmt.Errorf("hujson: patch operation %d: missing required member %q", i, "value")
+		case !seen["leading"] && !seen["trailing"] && op.op == "comment":
+			return nil, fmt.Errorf("hujson: patch operation %d: missing required member %q or %q", i, "leading", "trailing")
 		}
 		ops = append(ops, op)
 	}
 	return ops, nil
 }
 
-func (v *Value) patchAdd(i int, op patchOperation) error {
-	s, err := v.find(findState{pointer: op.path})
-	if err != nil && (err != errNotFound || len(s.pointer) != s.offset) {
+func (v *Value) patchAdd(c *findCache, i int, op patchOperation) error {
+	s, err := v.findPath(c, op.path)
+	if err != nil && (err != errNotFound || s.depth != len(s.pointer.tokens)) {
 		return fmt.Errorf("hujson: patch operation %d: %v", i, err)
 	}
 	if s.parent == nil {
 		*v = op.value // only occurs for root
+		c.mutated(nil)
 	} else {
 		switch comp := s.parent.(type) {
 		case *Object:
@@ -154,16 +269,18 @@ func (v *Value) patchAdd(i int, op patchOperation) error {
 			} else {
 				insertAt(comp, s.idx, op.value)
 				comp.Members[s.idx].Name.Value = String(s.name)
+				c.insertedMember(comp, s.idx)
 			}
-		case *Array:
-			insertAt(comp, s.idx, op.value)
+		case *Array, *arrayTree:
+			insertAt(c.resizable(comp), s.idx, op.value)
 		}
+		c.mutated(s.parent)
 	}
 	return nil
 }
 
-func (v *Value) patchRemoveOrReplace(i int, op patchOperation) error {
-	s, err := v.find(findState{pointer: op.path})
+func (v *Value) patchRemoveOrReplace(c *findCache, i int, op patchOperation) error {
+	s, err := v.findPath(c, op.path)
 	if err != nil {
 		return fmt.Errorf("hujson: patch operation %d: %v", i, err)
 	}
@@ -172,18 +289,27 @@ func (v *Value) patchRemoveOrReplace(i int, op patchOperation) error {
 	}
 	switch op.op {
 	case "remove":
-		removeAt(s.parent, s.idx)
+		if obj, ok := s.parent.(*Object); ok {
+			c.removingMember(obj, s.idx)
+		}
+		removeAt(c.resizable(s.parent), s.idx)
 	case "replace":
 		replaceAt(s.parent, s.idx, op.value)
 	}
+	c.mutated(s.parent)
 	return nil
 }
 
-func (v *Value) patchMoveOrCopy(i int, op patchOperation) error {
-	if op.from == "" || (op.op == "move" && hasPathPrefix(op.path, op.from)) {
+func (v *Value) patchMoveOrCopy(c *findCache, i int, op patchOperation) error {
+	from, errFrom := parsePointer(op.from)
+	path, errPath := parsePointer(op.path)
+	if op.from == "" || (op.op == "move" && errFrom == nil && errPath == nil && path.hasPrefix(from)) {
 		return fmt.Errorf("hujson: patch operation %d: cannot %s %q into %q", i, op.op, op.from, op.path)
 	}
-	sFrom, err := v.find(findState{pointer: op.from})
+	if errFrom != nil {
+		return fmt.Errorf("hujson: patch operation %d: %v", i, errFrom)
+	}
+	sFrom, err := v.find(findState{pointer: from, cache: c})
 	if err != nil {
 		return fmt.Errorf("hujson: patch operation %d: %v", i, err)
 	}
@@ -191,18 +317,24 @@ func (v *Value) patchMoveOrCopy(i int, op patchOperation) error {
 	// we should simplify this as just a rename or replace.
 	switch op.op {
 	case "move":
-		op.value = removeAt(sFrom.parent, sFrom.idx)
+		if obj, ok := sFrom.parent.(*Object); ok {
+			c.removingMember(obj, sFrom.idx)
+		}
+		op.value = removeAt(c.resizable(sFrom.parent), sFrom.idx)
+		c.mutated(sFrom.parent)
 	case "copy":
+		c.flushWithin(sFrom.value)
 		op.value = copyAt(sFrom.parent, sFrom.idx)
 	}
-	return v.patchAdd(i, op)
+	return v.patchAdd(c, i, op)
 }
 
-func (v *Value) patchTest(i int, op patchOperation) error {
-	s, err := v.find(findState{pointer: op.path})
+func (v *Value) patchTest(c *findCache, i int, op patchOperation) error {
+	s, err := v.findPath(c, op.path)
 	if err != nil {
 		return fmt.Errorf("hujson: patch operation %d: %v", i, err)
 	}
+	c.flushWithin(s.value)
 	if !equalValue(*s.value, op.value) {
 		return fmt.Errorf("hujson: patch operation %d: values differ at %q", i, op.path)
 
@@ -210,32 +342,47 @@ func (v *Value) patchTest(i int, op patchOperation) error {
 	return nil
 }
 
-// hasPathPrefix is a stricter version of strings.HasPrefix where
-// the prefix must end on a path segment boundary.
-func hasPathPrefix(s, prefix string) bool {
-	if strings.HasPrefix(s, prefix) {
-		return len(s) == len(prefix) || s[len(prefix)] == '/'
+func (v *Value) patchComment(c *findCache, i int, op patchOperation) error {
+	s, err := v.findPath(c, op.path)
+	if err != nil {
+		return fmt.Errorf("hujson: patch operation %d: %v", i, err)
+	}
+	if s.parent == nil {
+		return fmt.Errorf("hujson: patch operation %d: cannot %s root value", i, op.op)
+	}
+	if op.op == "uncomment" {
+		op.leading, op.trailing = Extra{}, Extra{}
+	}
+	if op.leading != nil {
+		s.parent.beforeExtraAt(s.idx + 0).replaceLeadingComments(op.leading)
 	}
-	return false
+	if op.trailing != nil {
+		b := s.parent.beforeExtraAt(s.idx + 1)
+		b.extractTrailingcomments(false)
+		b.injectTrailingComments(op.trailing)
+	}
+	return nil
 }
 
-func equalValue(x, y Value) bool {
-	// TODO(dsnet): This definition of equality is both naive and slow.
-	//	* It fails to properly compare strings with invalid UTF-8.
-	//	* It fails to precisely compare integers beyond ±2⁵³.
-	//	* It cannot handle values greater than ±math.MaxFloat64.
-	//	* Comparison of objects with duplicate names has undefined behavior.
-	unmarshal := func(v Value) (vi interface{}) {
-		v = v.Clone()
-		v.Standardize()
-		if json.Unmarshal(v.Pack(), &vi) != nil {
-			return nil
-		}
-		return vi
+// parseCommentMember parses the "leading" or "trailing" member of
+// a "comment" patch operation, which must only contain comments and whitespace.
+// The comments are returned with a single space before them and
+// only with a trailing newline if necessary to terminate a line comment.
+// It returns an empty, non-nil Extra if there are no comments.
+func parseCommentMember(s string) (Extra, bool) {
+	b := Extra(strings.Trim(s, " \t\r\n"))
+	if len(b) == 0 {
+		return Extra{}, true
 	}
-	vx := unmarshal(x)
-	vy := unmarshal(y)
-	return reflect.DeepEqual(vx, vy) && vx != nil && vy != nil
+	b = append(Extra(" "), b...)
+	if !b.IsValid() {
+		b = append(b, '\n') // permit an unterminated line comment
+	}
+	return b, b.IsValid()
+}
+
+func equalValue(x, y Value) bool {
+	return Equal(x, y, EqualOptions{})
 }
 
 func (obj *Object) getAt(i int) ValueTrimmed {
@@ -356,6 +503,24 @@ func removeAt(comp composite, i int) (v Value) {
 	return v
 }
 
+// replaceLeadingComments replaces the leading comments at the bottom of b
+// with the provided comments, or removes them if leading is empty.
+func (b *Extra) replaceLeadingComments(leading Extra) {
+	b.extractLeadingComments(false)
+	if len(leading) > 0 {
+		// Place the value on a new line with its original indentation.
+		leading = copyBytes(leading)
+		if !bytes.HasSuffix(leading, newline) {
+			leading = append(leading, '\n')
+		}
+		indent := (*b)[bytes.LastIndexByte(*b, '\n')+len("\n"):]
+		if consumeWhitespace(indent) == len(indent) {
+			leading = append(leading, indent...)
+		}
+		b.injectLeadingComments(leading)
+	}
+}
+
 // injectLeadingComments injects leading comments into the bottom of b.
 func (b *Extra) injectLeadingComments(leading Extra) {
 	if len(leading) > 0 {
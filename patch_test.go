@@ -5,10 +5,13 @@
 package hujson
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -238,20 +241,19 @@ var testdataPatch = []struct {
 }, {
 	in:      `"` + "\xff" + `"`,
 	patch:   `[{ "op": "test", "path": "", "value": "` + "\ufffd" + `" }]`,
-	wantErr: nil, // TODO(dsnet): Should fail due to invalid UTF-8.
+	wantErr: errors.New(`hujson: patch operation 0: values differ at ""`),
 }, {
 	in:      `9223372036854775800`,
 	patch:   `[{ "op": "test", "path": "", "value": 9223372036854775801 }]`,
-	wantErr: nil, // TODO(dsnet): Should fail under precise integer equality.
-}, {
-	in:    `1e1000`,
-	patch: `[{ "op": "test", "path": "", "value": 1e1000 }]`,
-	// TODO(dsnet): Should pass under comparison of closest floating-point values.
 	wantErr: errors.New(`hujson: patch operation 0: values differ at ""`),
+}, {
+	in:      `1e1000`,
+	patch:   `[{ "op": "test", "path": "", "value": 1e1000 }]`,
+	wantErr: nil,
 }, {
 	in:      `{ "dupe": "foo", "dupe": "bar" }`,
 	patch:   `[{ "op": "test", "path": "", "value": { "dupe": "bar" } }]`,
-	wantErr: nil, // TODO(dsnet): Should fail because of duplicate members.
+	wantErr: errors.New(`hujson: patch operation 0: values differ at ""`),
 }, {
 	in: `{
 	"name1": "value",
@@ -519,3 +521,457 @@ func TestPatch(t *testing.T) {
 		})
 	}
 }
+
+var testdataPatchComments = []struct {
+	in      string
+	patch   string
+	want    string
+	wantErr error
+}{{
+	in: `{
+	"name1": "value1",
+	"name2": "value2",
+}`,
+	patch: `[{ "op": "comment", "path": "/name2", "leading": "// managed by X" }]`,
+	want: `{
+	"name1": "value1",
+	// managed by X
+	"name2": "value2",
+}`,
+}, {
+	in: `{
+	"name1": "value1",
+	// Comment1
+	"name2": "value2", // Comment2
+	"name3": "value3",
+}`,
+	patch: `[{ "op": "comment", "path": "/name2", "leading": "/* Comment3 */", "trailing": "// Comment4" }]`,
+	want: `{
+	"name1": "value1",
+	/* Comment3 */
+	"name2": "value2", // Comment4
+	"name3": "value3",
+}`,
+}, {
+	in: `[
+	"value1",
+	// Comment1
+	"value2", // Comment2
+	"value3",
+]`,
+	patch: `[{ "op": "comment", "path": "/1", "trailing": "" }]`,
+	want: `[
+	"value1",
+	// Comment1
+	"value2",
+	"value3",
+]`,
+}, {
+	in: `[
+	"value1",
+
+	// Comment1
+
+	// Comment2
+	"value2", // Comment3
+	// Comment4
+
+	"value3",
+]`,
+	patch: `[{ "op": "uncomment", "path": "/1" }]`,
+	want: `[
+	"value1",
+
+	// Comment1
+
+	"value2",
+
+	"value3",
+]`,
+}, {
+	in:    `{"name1":"value1","name2":"value2"}`,
+	patch: `[{ "op": "comment", "path": "/name2", "leading": "// Comment1\n// Comment2\n", "trailing": "/* Comment3 */" }]`,
+	want: `{"name1":"value1",
+// Comment1
+// Comment2
+"name2":"value2" /* Comment3 */}`,
+}, {
+	in:      `{}`,
+	patch:   `[{ "op": "comment", "path": "", "leading": "// Comment" }]`,
+	wantErr: errors.New(`hujson: patch operation 0: cannot comment root value`),
+}, {
+	in:      `{}`,
+	patch:   `[{ "op": "uncomment", "path": "/noexist" }]`,
+	wantErr: errors.New(`hujson: patch operation 0: value not found`),
+}, {
+	in:      `{"name":"value"}`,
+	patch:   `[{ "op": "comment", "path": "/name" }]`,
+	wantErr: errors.New(`hujson: patch operation 0: missing required member "leading" or "trailing"`),
+}, {
+	in:      `{"name":"value"}`,
+	patch:   `[{ "op": "comment", "path": "/name", "leading": "not a comment" }]`,
+	wantErr: errors.New(`hujson: patch operation 0: member "leading" must only contain comments`),
+}, {
+	in:      `{"name":"value"}`,
+	patch:   `[{ "op": "comment", "path": "/name", "trailing": 5 }]`,
+	wantErr: errors.New(`hujson: patch operation 0: member "trailing" must be a JSON string`),
+}}
+
+func TestPatchComments(t *testing.T) {
+	for _, tt := range testdataPatchComments {
+		t.Run("", func(t *testing.T) {
+			v, err := Parse([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			gotErr := v.PatchWithOptions([]byte(tt.patch), PatchOptions{AllowCommentOps: true})
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("PatchWithOptions error mismatch:\ngot  %v\nwant %v", gotErr, tt.wantErr)
+			}
+			got := v.String()
+			if diff := cmp.Diff(tt.want, got); diff != "" && tt.want != "" {
+				t.Errorf("PatchWithOptions mismatch (-want +got):\n%s\n\ngot:\n%s\n\nwant:\n%s", diff, got, tt.want)
+			}
+		})
+	}
+
+	// Comment operations are rejected unless explicitly enabled.
+	v, _ := Parse([]byte(`{"name":"value"}`))
+	gotErr := v.Patch([]byte(`[{ "op": "comment", "path": "/name", "leading": "// Comment" }]`))
+	wantErr := errors.New(`hujson: patch operation 0: unknown operation "comment"`)
+	if !reflect.DeepEqual(gotErr, wantErr) {
+		t.Errorf("Patch error mismatch:\ngot  %v\nwant %v", gotErr, wantErr)
+	}
+}
+
+var testdataPatchPatterns = []struct {
+	in          string
+	patch       string
+	want        string
+	wantApplied []string
+	wantErr     error
+}{{
+	in: `{"hosts": {
+	"a": {"port": 80}, // Comment
+	"b": {"port": 81},
+}}`,
+	patch: `[{ "op": "replace", "path": "/hosts/*/port", "value": 443 }]`,
+	want: `{"hosts": {
+	"a": {"port": 443}, // Comment
+	"b": {"port": 443},
+}}`,
+	wantApplied: []string{"0:/hosts/b/port", "0:/hosts/a/port"},
+}, {
+	in:          `{"users": [{"name": "alice", "role": "admin"}, {"name": "bob", "role": "admin"}, {"name": "bob"}]}`,
+	patch:       `[{ "op": "remove", "path": "/users/[name=bob]/role" }]`,
+	wantErr:     errors.New(`hujson: patch operation 0: value not found`),
+	wantApplied: []string{},
+}, {
+	in:          `{"users": [{"name": "alice", "role": "admin"}, {"name": "bob", "role": "admin"}, {"name": "bob"}]}`,
+	patch:       `[{ "op": "add", "path": "/users/[name=bob]/role", "value": "user" }]`,
+	want:        `{"users": [{"name": "alice", "role": "admin"}, {"name": "bob", "role": "user"}, {"name": "bob","role":"user"}]}`,
+	wantApplied: []string{"0:/users/2/role", "0:/users/1/role"},
+}, {
+	in:          `[1, 2, 3]`,
+	patch:       `[{ "op": "remove", "path": "/*" }, { "op": "add", "path": "/-", "value": 4 }]`,
+	want:        `[4]`,
+	wantApplied: []string{"0:/2", "0:/1", "0:/0", "1:/-"},
+}, {
+	in:          `[{"id": 1, "on": true}, {"id": 2, "on": false}, {"id": "1"}]`,
+	patch:       `[{ "op": "remove", "path": "/[id=1]" }, { "op": "test", "path": "/[on=false]/id", "value": 2 }]`,
+	want:        `[ {"id": 2, "on": false}]`,
+	wantApplied: []string{"0:/2", "0:/0", "1:/0/id"},
+}, {
+	in:          `{"a/b": {"x": {"c~d": 1}}, "e": {"x": {"c~d": 2}}}`,
+	patch:       `[{ "op": "copy", "from": "/e/x", "path": "/[c~0d=1]/y" }]`,
+	want:        `{"a/b": {"x": {"c~d": 1}}, "e": {"x": {"c~d": 2}}}`,
+	wantErr:     errors.New(`hujson: patch operation 0: no values match "/[c~0d=1]/y"`),
+	wantApplied: []string{},
+}, {
+	in:          `{"a/b": {"x": {"c~d": 1}}, "e": {"x": {"c~d": 2}}}`,
+	patch:       `[{ "op": "copy", "from": "/e/x", "path": "/*/x/[c~0d=1]" }]`,
+	want:        `{"a/b": {"x": {"c~d": 1}}, "e": {"x": {"c~d": 2}}}`,
+	wantErr:     errors.New(`hujson: patch operation 0: no values match "/*/x/[c~0d=1]"`),
+	wantApplied: []string{},
+}, {
+	in:          `{"a/b": {"x": {"c~d": 1}}, "e": {"x": {"c~d": 2}}}`,
+	patch:       `[{ "op": "copy", "from": "/e/x", "path": "/*/y" }]`,
+	want:        `{"a/b": {"x": {"c~d": 1},"y":{"c~d": 2}}, "e": {"x": {"c~d": 2},"y":{"c~d": 2}}}`,
+	wantApplied: []string{"0:/e/y", "0:/a~1b/y"},
+}, {
+	in:          `{"a": {"v": 1}, "a": {"v": 2}}`,
+	patch:       `[{ "op": "replace", "path": "/*/v", "value": 0 }]`,
+	want:        `{"a": {"v": 0}, "a": {"v": 2}}`,
+	wantApplied: []string{"0:/a/v"},
+}, {
+	in:          `{"a": [1], "b": [2]}`,
+	patch:       `[{ "op": "add", "path": "/*/-", "value": {"new": [] } }]`,
+	want:        `{"a": [1,{"new": [] }], "b": [2,{"new": [] }]}`,
+	wantApplied: []string{"0:/b/-", "0:/a/-"},
+}, {
+	in:          `{"a": [1]}`,
+	patch:       `[{ "op": "move", "from": "/a/0", "path": "/*" }]`,
+	want:        `{"a": [1]}`,
+	wantErr:     errors.New(`hujson: patch operation 0: cannot move into pattern "/*"`),
+	wantApplied: []string{},
+}, {
+	in:          `{"*": 1}`,
+	patch:       `[{ "op": "test", "path": "/*", "value": 1 }]`,
+	want:        `{"*": 1}`,
+	wantApplied: []string{"0:/*"},
+}}
+
+func TestPatchPatterns(t *testing.T) {
+	for _, tt := range testdataPatchPatterns {
+		t.Run("", func(t *testing.T) {
+			v, err := Parse([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			gotApplied := []string{}
+			gotErr := v.PatchWithOptions([]byte(tt.patch), PatchOptions{
+				AllowPatterns: true,
+				Applied: func(op int, pointer string) {
+					gotApplied = append(gotApplied, fmt.Sprintf("%d:%s", op, pointer))
+				},
+			})
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("PatchWithOptions error mismatch:\ngot  %v\nwant %v", gotErr, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.wantApplied, gotApplied); diff != "" {
+				t.Errorf("applied pointers mismatch (-want +got):\n%s", diff)
+			}
+			if tt.want == "" {
+				return
+			}
+			got := v.String()
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("PatchWithOptions mismatch (-want +got):\n%s\n\ngot:\n%s\n\nwant:\n%s", diff, got, tt.want)
+			}
+		})
+	}
+
+	// Patterns are treated as regular pointers unless explicitly enabled.
+	v, _ := Parse([]byte(`{"a": 1, "*": 2}`))
+	if err := v.Patch([]byte(`[{ "op": "remove", "path": "/*" }]`)); err != nil {
+		t.Fatalf("Patch error: %v", err)
+	}
+	if got, want := v.String(), `{"a": 1}`; got != want {
+		t.Errorf("Patch = %s, want %s", got, want)
+	}
+}
+
+// TestPatchAppliedInspect verifies that the value is up to date
+// when inspected within PatchOptions.Applied.
+func TestPatchAppliedInspect(t *testing.T) {
+	in, _ := json.Marshal(map[string][]int{"items": make([]int, minTreeElements)})
+	v, err := Parse(in)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	var ops []string
+	for i := 0; i < 10; i++ {
+		ops = append(ops, fmt.Sprintf(`{"op": "add", "path": "/items/%d", "value": %d}`, i, i+1))
+	}
+	err = v.PatchWithOptions([]byte("["+strings.Join(ops, ",")+"]"), PatchOptions{
+		Applied: func(op int, pointer string) {
+			got, err := v.Get("/items")
+			if err != nil {
+				t.Fatalf("Get error: %v", err)
+			}
+			if n, want := len(got.([]interface{})), minTreeElements+op+1; n != want {
+				t.Errorf("operation %d: len(items) = %d, want %d", op, n, want)
+			}
+			if got, _ := v.Get(pointer); got != float64(op+1) {
+				t.Errorf("operation %d: Get(%q) = %v, want %d", op, pointer, got, op+1)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("PatchWithOptions error: %v", err)
+	}
+}
+
+// TestPatchCache verifies that applying many operations in a single patch,
+// which reuses cached lookup state, matches applying each operation
+// in a separate patch.
+func TestPatchCache(t *testing.T) {
+	var in strings.Builder
+	in.WriteString("{\n")
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&in, "\t\"k%d\": {\"v\": %d, \"a\": [0, 1, 2]},\n", i%30, i) // some names are duplicated
+	}
+	in.WriteString("}\n")
+
+	var ops []string
+	for i := 0; i < 10; i++ {
+		ops = append(ops,
+			fmt.Sprintf(`{"op": "test", "path": "/k%d/v", "value": %d}`, 10+i, 10+i),
+			fmt.Sprintf(`{"op": "remove", "path": "/k%d"}`, i),
+			fmt.Sprintf(`{"op": "test", "path": "/k%d/v", "value": %d}`, i, 30+i),
+			fmt.Sprintf(`{"op": "add", "path": "/n%d", "value": {"v": %d}}`, i, i),
+			fmt.Sprintf(`{"op": "move", "from": "/k%d/a/1", "path": "/n%d/a"}`, 11+i%8, i),
+			fmt.Sprintf(`{"op": "copy", "from": "/n%d", "path": "/k%d/a/-"}`, i, 12+i%8),
+			fmt.Sprintf(`{"op": "replace", "path": "/k%d/v", "value": "x"}`, 10+i),
+			fmt.Sprintf(`{"op": "move", "from": "/k%d", "path": "/m%d"}`, 20+i, i),
+		)
+	}
+
+	got, err := Parse([]byte(in.String()))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	want := got.Clone()
+	gotErr := got.Patch([]byte("[" + strings.Join(ops, ",") + "]"))
+	var wantErr error
+	for _, op := range ops {
+		if wantErr = want.Patch([]byte("[" + op + "]")); wantErr != nil {
+			break
+		}
+	}
+	if gotErr != nil || wantErr != nil {
+		t.Fatalf("Patch error:\ngot  %v\nwant %v", gotErr, wantErr)
+	}
+	if diff := cmp.Diff(want.String(), got.String()); diff != "" {
+		t.Errorf("Patch mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// BenchmarkPatch applies patches with many related operations to a large
+// document. The time per operation should remain roughly constant
+// regardless of the number of operations or the size of the document.
+// Each iteration applies a single patch with the number of operations
+// given in the benchmark name.
+func BenchmarkPatch(b *testing.B) {
+	for _, n := range []int{1e3, 1e4, 1e5} {
+		var in strings.Builder
+		in.WriteString(`{"hosts": {`)
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&in, `"host%d": {"port": %d, "tags": ["a", "b"]},`, i, i)
+		}
+		in.WriteString(`}}`)
+		var patch strings.Builder
+		patch.WriteString(`[`)
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&patch, `{"op": "test", "path": "/hosts/host%d/port", "value": %d},`, i, i)
+			fmt.Fprintf(&patch, `{"op": "replace", "path": "/hosts/host%d/port", "value": %d},`, i, i+1)
+			fmt.Fprintf(&patch, `{"op": "add", "path": "/hosts/host%d/tags/-", "value": "c"},`, i)
+			fmt.Fprintf(&patch, `{"op": "add", "path": "/hosts/new%d", "value": {"port": %d}},`, i, i)
+		}
+		patch.WriteString(`]`)
+		numOps := 4 * n
+
+		v, err := Parse([]byte(in.String()))
+		if err != nil {
+			b.Fatalf("Parse error: %v", err)
+		}
+		patchBytes := []byte(patch.String())
+		b.Run(fmt.Sprintf("Ops%d", numOps), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				v2, patch := v.Clone(), copyBytes(patchBytes)
+				b.StartTimer()
+				if err := v2.Patch(patch); err != nil {
+					b.Fatalf("Patch error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestPatchArrayTree verifies patches that resize large arrays
+// against a simple model of the expected elements.
+func TestPatchArrayTree(t *testing.T) {
+	rn := rand.New(rand.NewSource(0))
+	model := map[string][]int{"a": nil, "b": nil}
+	for i := 0; i < 2*minTreeElements; i++ {
+		model["a"] = append(model["a"], i)
+		model["b"] = append(model["b"], -i)
+	}
+	in, _ := json.Marshal(model)
+	v, err := Parse(in)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	var ops []string
+	names := []string{"a", "b"}
+	for i := 0; i < 2000; i++ {
+		name := names[rn.Intn(2)]
+		arr := model[name]
+		j := rn.Intn(len(arr))
+		switch rn.Intn(6) {
+		case 0:
+			ops = append(ops, fmt.Sprintf(`{"op": "add", "path": "/%s/%d", "value": %d}`, name, j, 1000+i))
+			arr = append(arr[:j], append([]int{1000 + i}, arr[j:]...)...)
+		case 1:
+			ops = append(ops, fmt.Sprintf(`{"op": "remove", "path": "/%s/%d"}`, name, j))
+			arr = append(arr[:j], arr[j+1:]...)
+		case 2:
+			ops = append(ops, fmt.Sprintf(`{"op": "replace", "path": "/%s/%d", "value": %d}`, name, j, 1000+i))
+			arr[j] = 1000 + i
+		case 3:
+			ops = append(ops, fmt.Sprintf(`{"op": "test", "path": "/%s/%d", "value": %d}`, name, j, arr[j]))
+		case 4:
+			other := names[(rn.Intn(2))]
+			ops = append(ops, fmt.Sprintf(`{"op": "move", "from": "/%s/%d", "path": "/%s/-"}`, name, j, other))
+			x := arr[j]
+			arr = append(arr[:j], arr[j+1:]...)
+			model[name] = arr
+			arr, name = append(model[other], x), other
+		case 5:
+			ops = append(ops, fmt.Sprintf(`{"op": "copy", "from": "/%s/%d", "path": "/%s/0"}`, name, j, name))
+			arr = append([]int{arr[j]}, arr...)
+		}
+		model[name] = arr
+		if i%500 == 0 {
+			b, _ := json.Marshal(model[name])
+			ops = append(ops, fmt.Sprintf(`{"op": "test", "path": "/%s", "value": %s}`, name, b))
+		}
+	}
+	if err := v.Patch([]byte("[" + strings.Join(ops, ",") + "]")); err != nil {
+		t.Fatalf("Patch error: %v", err)
+	}
+	var got map[string][]int
+	if err := json.Unmarshal(v.Pack(), &got); err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+	if diff := cmp.Diff(model, got); diff != "" {
+		t.Errorf("Patch mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// BenchmarkPatchArray applies patches that insert and remove elements
+// at arbitrary positions within a large array. The time per operation
+// should grow only logarithmically with the size of the array.
+// Each iteration applies a single patch with the number of operations
+// given in the benchmark name.
+func BenchmarkPatchArray(b *testing.B) {
+	for _, n := range []int{1e3, 1e4, 1e5} {
+		in, _ := json.Marshal(map[string][]int{"items": make([]int, n)})
+		var patch strings.Builder
+		patch.WriteString(`[`)
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&patch, `{"op": "add", "path": "/items/%d", "value": %d},`, (i*7919)%n, i)
+			fmt.Fprintf(&patch, `{"op": "remove", "path": "/items/%d"},`, (i*104729)%n)
+		}
+		patch.WriteString(`]`)
+		numOps := 2 * n
+
+		v, err := Parse(in)
+		if err != nil {
+			b.Fatalf("Parse error: %v", err)
+		}
+		patchBytes := []byte(patch.String())
+		b.Run(fmt.Sprintf("Ops%d", numOps), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				v2, patch := v.Clone(), copyBytes(patchBytes)
+				b.StartTimer()
+				if err := v2.Patch(patch); err != nil {
+					b.Fatalf("Patch error: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pointer is a parsed JSON Pointer (see RFC 6901), which identifies
+// a specific value within a JSON value as a sequence of reference tokens.
+// The zero value is the empty pointer, which refers to the root value.
+//
+// A Pointer is immutable. Methods that derive a new pointer
+// never modify the original.
+type Pointer struct {
+	tokens []string // unescaped reference tokens
+}
+
+// ParsePointer parses the string representation of a JSON pointer,
+// where "~1" and "~0" within each reference token denote "/" and "~".
+func ParsePointer(s string) (Pointer, error) {
+	p, err := parsePointer(s)
+	if err != nil {
+		return Pointer{}, fmt.Errorf("hujson: %w", err)
+	}
+	return p, nil
+}
+
+func parsePointer(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return Pointer{}, fmt.Errorf("invalid pointer: lacks a forward slash prefix")
+	}
+	tokens := strings.Split(s[len("/"):], "/")
+	for i, token := range tokens {
+		tokens[i] = unescapePointerToken(token)
+	}
+	return Pointer{tokens}, nil
+}
+
+// Append returns a pointer to the member or element of the value at p
+// identified by the (unescaped) reference token.
+func (p Pointer) Append(token string) Pointer {
+	return Pointer{append(p.tokens[:len(p.tokens):len(p.tokens)], token)}
+}
+
+// Parent returns a pointer to the value that contains the value at p.
+// The parent of the empty pointer is itself.
+func (p Pointer) Parent() Pointer {
+	if len(p.tokens) == 0 {
+		return p
+	}
+	return Pointer{p.tokens[: len(p.tokens)-1 : len(p.tokens)-1]}
+}
+
+// Tokens returns the unescaped reference tokens of p.
+func (p Pointer) Tokens() []string {
+	return append([]string(nil), p.tokens...)
+}
+
+// String returns the string representation of p,
+// escaping "~" and "/" within each reference token.
+func (p Pointer) String() string {
+	var b strings.Builder
+	for _, token := range p.tokens {
+		b.WriteByte('/')
+		b.WriteString(escapePointerToken(token))
+	}
+	return b.String()
+}
+
+// hasPrefix reports whether the tokens of prefix are
+// the leading tokens of p.
+func (p Pointer) hasPrefix(prefix Pointer) bool {
+	if len(prefix.tokens) > len(p.tokens) {
+		return false
+	}
+	for i, token := range prefix.tokens {
+		if p.tokens[i] != token {
+			return false
+		}
+	}
+	return true
+}
+
+// escapePointerToken escapes a JSON pointer reference token
+// per RFC 6901, section 3.
+func escapePointerToken(s string) string {
+	if strings.IndexAny(s, "~/") < 0 {
+		return s
+	}
+	s = strings.ReplaceAll(s, "~", "~0")
+	return strings.ReplaceAll(s, "/", "~1")
+}
+
+// unescapePointerToken unescapes a JSON pointer reference token
+// per RFC 6901, section 4.
+func unescapePointerToken(s string) string {
+	if strings.IndexByte(s, '~') < 0 {
+		return s
+	}
+	s = strings.ReplaceAll(s, "~1", "/")
+	return strings.ReplaceAll(s, "~0", "~")
+}
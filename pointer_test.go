@@ -0,0 +1,83 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPointer(t *testing.T) {
+	tests := []struct {
+		in         string
+		wantTokens []string
+		wantParent string
+		wantErr    bool
+	}{
+		{in: "", wantTokens: nil, wantParent: ""},
+		{in: "/", wantTokens: []string{""}, wantParent: ""},
+		{in: "/a/b", wantTokens: []string{"a", "b"}, wantParent: "/a"},
+		{in: "/a~1b/c~0d/~01", wantTokens: []string{"a/b", "c~d", "~1"}, wantParent: "/a~1b/c~0d"},
+		{in: "//", wantTokens: []string{"", ""}, wantParent: "/"},
+		{in: "/0/-", wantTokens: []string{"0", "-"}, wantParent: "/0"},
+		{in: "a", wantErr: true},
+	}
+	for _, tt := range tests {
+		p, err := ParsePointer(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePointer(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if diff := cmp.Diff(tt.wantTokens, p.Tokens()); diff != "" {
+			t.Errorf("ParsePointer(%q).Tokens() mismatch (-want +got):\n%s", tt.in, diff)
+		}
+		if got := p.String(); got != tt.in {
+			t.Errorf("ParsePointer(%q).String() = %q, want %q", tt.in, got, tt.in)
+		}
+		if got := p.Parent().String(); got != tt.wantParent {
+			t.Errorf("ParsePointer(%q).Parent() = %q, want %q", tt.in, got, tt.wantParent)
+		}
+	}
+
+	// Pointers derived from the same pointer must not share tokens.
+	var root Pointer
+	base := root.Append("a").Append("b").Parent()
+	x, y := base.Append("x"), base.Append("y")
+	if got, want := x.String()+" "+y.String(), "/a/x /a/y"; got != want {
+		t.Errorf("Append = %q, want %q", got, want)
+	}
+	tokens := x.Tokens()
+	tokens[0] = "modified"
+	if got, want := x.String(), "/a/x"; got != want {
+		t.Errorf("Tokens aliases pointer: got %q, want %q", got, want)
+	}
+	if got, want := root.Append("a/b").Append("~").String(), "/a~1b/~0"; got != want {
+		t.Errorf("Append = %q, want %q", got, want)
+	}
+}
+
+func TestFindPointer(t *testing.T) {
+	v, err := Parse([]byte(`{"a/b": [0, {"~": "x"}]}`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	var root Pointer
+	p := root.Append("a/b").Append("1").Append("~")
+	if got := v.FindPointer(p); got == nil || got.String() != ` "x"` {
+		t.Errorf("FindPointer(%v) = %v, want %q", p, got, ` "x"`)
+	}
+	if got, want := v.FindPointer(p), v.Find(p.String()); got != want {
+		t.Errorf("FindPointer(%v) = %p, want %p", p, got, want)
+	}
+	if got := v.FindPointer(p.Append("x")); got != nil {
+		t.Errorf("FindPointer(%v) = %v, want nil", p.Append("x"), got)
+	}
+	if got := v.FindPointer(root); got != &v {
+		t.Errorf("FindPointer(%v) = %p, want %p", root, got, &v)
+	}
+}
@@ -0,0 +1,1151 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Query evaluates a JSONPath expression (see RFC 9535) against v
+// and returns the selected values in the order that they were selected.
+// The returned values point into v such that they may be modified in place.
+//
+// All selectors (name, wildcard, index, slice, and filter) and segments
+// (child and descendant) are supported. Filter expressions may use
+// comparisons, logical operators, existence tests, and the standard functions
+// length, count, match, search, and value.
+// If a JSON object has multiple members matching a given name,
+// the first is selected.
+func (v *Value) Query(expr string) ([]*Value, error) {
+	nodes, err := v.query(expr, false)
+	if err != nil {
+		return nil, err
+	}
+	var vals []*Value
+	for _, n := range nodes {
+		vals = append(vals, n.value)
+	}
+	return vals, nil
+}
+
+// QueryPaths is like Query, but returns a JSON pointer (see RFC 6901)
+// to each selected value instead of the value itself.
+// The pointers may be passed to Find to obtain the values.
+func (v *Value) QueryPaths(expr string) ([]string, error) {
+	nodes, err := v.query(expr, true)
+	if err != nil {
+		return nil, err
+	}
+	var ptrs []string
+	for _, n := range nodes {
+		ptrs = append(ptrs, n.pointer)
+	}
+	return ptrs, nil
+}
+
+func (v *Value) query(expr string, withPointers bool) ([]queryNode, error) {
+	p := queryParser{in: expr}
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	e := queryEvaluator{root: v, withPointers: withPointers}
+	return e.evalSegments([]queryNode{{value: v}}, q.segments), nil
+}
+
+// queryNode is a value selected by a query and the pointer to it.
+type queryNode struct {
+	value   *Value
+	pointer string // only populated if queryEvaluator.withPointers is set
+}
+
+// pathQuery is a parsed JSONPath query.
+type pathQuery struct {
+	relative bool // whether the query is relative to the current node ("@")
+	segments []pathSegment
+}
+
+// isSingular reports whether the query selects at most one node.
+func (q pathQuery) isSingular() bool {
+	for _, seg := range q.segments {
+		if seg.descendant || len(seg.selectors) != 1 {
+			return false
+		}
+		switch seg.selectors[0].(type) {
+		case nameSelector, indexSelector:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+type pathSegment struct {
+	descendant bool
+	selectors  []pathSelector
+}
+
+// pathSelector is one of the following concrete types:
+// nameSelector, wildcardSelector, indexSelector, sliceSelector,
+// or filterSelector.
+type pathSelector interface{}
+
+type (
+	nameSelector     string
+	wildcardSelector struct{}
+	indexSelector    int64
+	sliceSelector    struct {
+		start, end       int64
+		hasStart, hasEnd bool
+		step             int64
+	}
+	filterSelector struct{ expr logicalExpr }
+)
+
+// logicalExpr is one of the following concrete types:
+// orExpr, andExpr, notExpr, existsExpr, functionExpr, or comparisonExpr.
+type logicalExpr interface{}
+
+type (
+	orExpr         []logicalExpr
+	andExpr        []logicalExpr
+	notExpr        struct{ expr logicalExpr }
+	existsExpr     struct{ query pathQuery }
+	comparisonExpr struct {
+		op          string
+		left, right comparableExpr
+	}
+)
+
+// comparableExpr is one of the following concrete types:
+// literalExpr, pathQuery (which must be singular), or functionExpr.
+type comparableExpr interface{}
+
+// literalExpr is a JSON value in a filter expression,
+// represented as nil, bool, float64, string, []interface{},
+// or map[string]interface{}.
+type literalExpr struct{ value interface{} }
+
+// functionType is the type of a function parameter or result.
+type functionType int
+
+const (
+	valueType functionType = iota
+	logicalType
+	nodesType
+)
+
+type functionExpr struct {
+	name string
+	args []interface{} // each is a comparableExpr or pathQuery
+}
+
+// queryFunctions are the function extensions defined in RFC 9535, section 2.4.
+var queryFunctions = map[string]struct {
+	params []functionType
+	result functionType
+}{
+	"length": {[]functionType{valueType}, valueType},
+	"count":  {[]functionType{nodesType}, valueType},
+	"match":  {[]functionType{valueType, valueType}, logicalType},
+	"search": {[]functionType{valueType, valueType}, logicalType},
+	"value":  {[]functionType{nodesType}, valueType},
+}
+
+// maxSafeInteger is the largest integer permitted as an index or
+// slice argument, as specified in RFC 9535, section 2.1.
+const maxSafeInteger = 1<<53 - 1
+
+type queryParser struct {
+	in  string
+	pos int
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("hujson: invalid JSONPath query at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *queryParser) peek() byte {
+	if p.pos < len(p.in) {
+		return p.in[p.pos]
+	}
+	return 0
+}
+
+func (p *queryParser) consume(s string) bool {
+	if strings.HasPrefix(p.in[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) skipBlank() {
+	for p.pos < len(p.in) && strings.IndexByte(" \t\n\r", p.in[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *queryParser) parseQuery() (pathQuery, error) {
+	if !p.consume("$") {
+		return pathQuery{}, p.errorf("must start with '$'")
+	}
+	q, err := p.parseSegments(false)
+	if err != nil {
+		return q, err
+	}
+	if p.pos < len(p.in) {
+		return q, p.errorf("unexpected character %q", p.in[p.pos])
+	}
+	return q, nil
+}
+
+// parseSegments parses the segments that follow a root identifier.
+func (p *queryParser) parseSegments(relative bool) (pathQuery, error) {
+	q := pathQuery{relative: relative}
+	for {
+		start := p.pos
+		p.skipBlank()
+		var seg pathSegment
+		switch {
+		case p.consume(".."):
+			seg.descendant = true
+			switch {
+			case p.peek() == '[':
+				sels, err := p.parseBracketedSelection()
+				if err != nil {
+					return q, err
+				}
+				seg.selectors = sels
+			case p.consume("*"):
+				seg.selectors = []pathSelector{wildcardSelector{}}
+			default:
+				name, err := p.parseMemberNameShorthand()
+				if err != nil {
+					return q, err
+				}
+				seg.selectors = []pathSelector{nameSelector(name)}
+			}
+		case p.consume("."):
+			if p.consume("*") {
+				seg.selectors = []pathSelector{wildcardSelector{}}
+				break
+			}
+			name, err := p.parseMemberNameShorthand()
+			if err != nil {
+				return q, err
+			}
+			seg.selectors = []pathSelector{nameSelector(name)}
+		case p.peek() == '[':
+			sels, err := p.parseBracketedSelection()
+			if err != nil {
+				return q, err
+			}
+			seg.selectors = sels
+		default:
+			p.pos = start // blank space is not part of the query
+			return q, nil
+		}
+		q.segments = append(q.segments, seg)
+	}
+}
+
+func (p *queryParser) parseMemberNameShorthand() (string, error) {
+	start := p.pos
+	for p.pos < len(p.in) {
+		r, n := utf8.DecodeRuneInString(p.in[p.pos:])
+		isFirst := r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') ||
+			(r >= 0x80 && !(r == utf8.RuneError && n == 1))
+		if !isFirst && !(p.pos > start && '0' <= r && r <= '9') {
+			break
+		}
+		p.pos += n
+	}
+	if p.pos == start {
+		return "", p.errorf("invalid member name")
+	}
+	return p.in[start:p.pos], nil
+}
+
+func (p *queryParser) parseBracketedSelection() ([]pathSelector, error) {
+	p.consume("[")
+	var sels []pathSelector
+	for {
+		p.skipBlank()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+		p.skipBlank()
+		switch {
+		case p.consume(","):
+			continue
+		case p.consume("]"):
+			return sels, nil
+		default:
+			return nil, p.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+func (p *queryParser) parseSelector() (pathSelector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		s, err := p.parseStringLiteral()
+		return nameSelector(s), err
+	case c == '*':
+		p.pos++
+		return wildcardSelector{}, nil
+	case c == '?':
+		p.pos++
+		p.skipBlank()
+		expr, err := p.parseLogicalOr()
+		return filterSelector{expr}, err
+	}
+
+	// Parse an index or slice selector.
+	var sel sliceSelector
+	var err error
+	if c := p.peek(); c == '-' || ('0' <= c && c <= '9') {
+		if sel.start, err = p.parseInteger(); err != nil {
+			return nil, err
+		}
+		sel.hasStart = true
+		p.skipBlank()
+	}
+	if !p.consume(":") {
+		if !sel.hasStart {
+			return nil, p.errorf("invalid selector")
+		}
+		return indexSelector(sel.start), nil
+	}
+	p.skipBlank()
+	if c := p.peek(); c == '-' || ('0' <= c && c <= '9') {
+		if sel.end, err = p.parseInteger(); err != nil {
+			return nil, err
+		}
+		sel.hasEnd = true
+		p.skipBlank()
+	}
+	sel.step = 1
+	if p.consume(":") {
+		p.skipBlank()
+		if c := p.peek(); c == '-' || ('0' <= c && c <= '9') {
+			if sel.step, err = p.parseInteger(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sel, nil
+}
+
+// parseInteger parses an integer without leading zeros
+// that is within the range of exactly representable integers.
+func (p *queryParser) parseInteger() (int64, error) {
+	start := p.pos
+	p.consume("-")
+	digits := p.pos
+	for p.pos < len(p.in) && '0' <= p.in[p.pos] && p.in[p.pos] <= '9' {
+		p.pos++
+	}
+	s := p.in[start:p.pos]
+	switch {
+	case p.pos == digits:
+		return 0, p.errorf("invalid integer")
+	case p.in[digits] == '0' && (p.pos-digits > 1 || digits > start):
+		return 0, p.errorf("invalid integer %q", s)
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < -maxSafeInteger || n > maxSafeInteger {
+		return 0, p.errorf("integer %s out of range", s)
+	}
+	return n, nil
+}
+
+// parseStringLiteral parses a single- or double-quoted string literal.
+func (p *queryParser) parseStringLiteral() (string, error) {
+	quote := p.in[p.pos]
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.in) {
+			return "", p.errorf("unterminated string literal")
+		}
+		c := p.in[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c < 0x20:
+			return "", p.errorf("invalid control character in string literal")
+		case c != '\\':
+			r, n := utf8.DecodeRuneInString(p.in[p.pos:])
+			if r == utf8.RuneError && n == 1 {
+				return "", p.errorf("invalid UTF-8 in string literal")
+			}
+			b.WriteString(p.in[p.pos : p.pos+n])
+			p.pos += n
+			continue
+		}
+
+		// Handle escape sequences.
+		p.pos++
+		switch c := p.peek(); c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '/', '\\':
+			b.WriteByte(c)
+		case '\'', '"':
+			if c != quote {
+				return "", p.errorf("invalid escape sequence")
+			}
+			b.WriteByte(c)
+		case 'u':
+			r, err := p.parseHexEscape()
+			if err != nil {
+				return "", err
+			}
+			if utf16.IsSurrogate(r) {
+				if r >= 0xdc00 || !p.consume(`\`) || p.peek() != 'u' {
+					return "", p.errorf("invalid surrogate pair")
+				}
+				r2, err := p.parseHexEscape()
+				if err != nil {
+					return "", err
+				}
+				if r = utf16.DecodeRune(r, r2); r == utf8.RuneError {
+					return "", p.errorf("invalid surrogate pair")
+				}
+			}
+			b.WriteRune(r)
+			continue
+		default:
+			return "", p.errorf("invalid escape sequence")
+		}
+		p.pos++
+	}
+}
+
+// parseHexEscape parses the "uXXXX" portion of a Unicode escape.
+func (p *queryParser) parseHexEscape() (rune, error) {
+	if p.pos+len("uXXXX") > len(p.in) {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	n, err := strconv.ParseUint(p.in[p.pos+len("u"):p.pos+len("uXXXX")], 16, 16)
+	if err != nil {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	p.pos += len("uXXXX")
+	return rune(n), nil
+}
+
+func (p *queryParser) parseLogicalOr() (logicalExpr, error) {
+	var or orExpr
+	for {
+		and, err := p.parseLogicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, and)
+		start := p.pos
+		p.skipBlank()
+		if !p.consume("||") {
+			p.pos = start
+			break
+		}
+		p.skipBlank()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *queryParser) parseLogicalAnd() (logicalExpr, error) {
+	var and andExpr
+	for {
+		expr, err := p.parseBasicExpr()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+		start := p.pos
+		p.skipBlank()
+		if !p.consume("&&") {
+			p.pos = start
+			break
+		}
+		p.skipBlank()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *queryParser) parseBasicExpr() (logicalExpr, error) {
+	if p.consume("!") {
+		p.skipBlank()
+		var expr logicalExpr
+		var err error
+		if p.peek() == '(' {
+			expr, err = p.parseParenExpr()
+		} else {
+			expr, err = p.parseTestExpr()
+		}
+		return notExpr{expr}, err
+	}
+	if p.peek() == '(' {
+		return p.parseParenExpr()
+	}
+
+	start := p.pos
+	left, err := p.parseComparable()
+	if err != nil {
+		return nil, err
+	}
+	end := p.pos
+	p.skipBlank()
+	var op string
+	for _, s := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(s) {
+			op = s
+			break
+		}
+	}
+	if op == "" {
+		p.pos = start
+		return p.parseTestExpr()
+	}
+	p.pos = end
+	if err := p.checkComparable(left, start); err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	p.pos += len(op)
+	p.skipBlank()
+	start = p.pos
+	right, err := p.parseComparable()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkComparable(right, start); err != nil {
+		return nil, err
+	}
+	return comparisonExpr{op, left, right}, nil
+}
+
+func (p *queryParser) parseParenExpr() (logicalExpr, error) {
+	p.consume("(")
+	p.skipBlank()
+	expr, err := p.parseLogicalOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if !p.consume(")") {
+		return nil, p.errorf("expected ')'")
+	}
+	return expr, nil
+}
+
+// parseTestExpr parses a filter query or function expression
+// that is used as a logical expression.
+func (p *queryParser) parseTestExpr() (logicalExpr, error) {
+	start := p.pos
+	switch expr, err := p.parseComparable(); expr := expr.(type) {
+	case nil:
+		return nil, err
+	case pathQuery:
+		return existsExpr{expr}, nil
+	case functionExpr:
+		if queryFunctions[expr.name].result == valueType {
+			p.pos = start
+			return nil, p.errorf("result of %s() must be compared", expr.name)
+		}
+		return expr, nil
+	default:
+		p.pos = start
+		return nil, p.errorf("literal must be compared")
+	}
+}
+
+// checkComparable checks that expr, which was parsed at offset start,
+// may be used as an operand of a comparison.
+func (p *queryParser) checkComparable(expr comparableExpr, start int) error {
+	pos := p.pos
+	defer func() { p.pos = pos }()
+	p.pos = start
+	switch expr := expr.(type) {
+	case pathQuery:
+		if !expr.isSingular() {
+			return p.errorf("query in comparison must be singular")
+		}
+	case functionExpr:
+		if queryFunctions[expr.name].result != valueType {
+			return p.errorf("result of %s() cannot be compared", expr.name)
+		}
+	}
+	return nil
+}
+
+// parseComparable parses a literal, filter query, or function expression.
+func (p *queryParser) parseComparable() (comparableExpr, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		return p.parseSegments(c == '@')
+	case c == '\'' || c == '"':
+		s, err := p.parseStringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return literalExpr{s}, nil
+	case c == '-' || ('0' <= c && c <= '9'):
+		return p.parseNumberLiteral()
+	case p.consume("true"):
+		return literalExpr{true}, nil
+	case p.consume("false"):
+		return literalExpr{false}, nil
+	case p.consume("null"):
+		return literalExpr{nil}, nil
+	case 'a' <= c && c <= 'z':
+		return p.parseFunctionExpr()
+	default:
+		return nil, p.errorf("invalid filter expression")
+	}
+}
+
+func (p *queryParser) parseNumberLiteral() (comparableExpr, error) {
+	start := p.pos
+	p.consume("-")
+	digits := p.pos
+	for p.pos < len(p.in) && '0' <= p.in[p.pos] && p.in[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == digits || (p.in[digits] == '0' && p.pos-digits > 1) {
+		return nil, p.errorf("invalid number")
+	}
+	if p.consume(".") {
+		n := p.pos
+		for p.pos < len(p.in) && '0' <= p.in[p.pos] && p.in[p.pos] <= '9' {
+			p.pos++
+		}
+		if p.pos == n {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	if p.consume("e") || p.consume("E") {
+		_ = p.consume("+") || p.consume("-")
+		n := p.pos
+		for p.pos < len(p.in) && '0' <= p.in[p.pos] && p.in[p.pos] <= '9' {
+			p.pos++
+		}
+		if p.pos == n {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	f, err := strconv.ParseFloat(p.in[start:p.pos], 64)
+	if err != nil {
+		return nil, p.errorf("invalid number %s", p.in[start:p.pos])
+	}
+	return literalExpr{f}, nil
+}
+
+func (p *queryParser) parseFunctionExpr() (comparableExpr, error) {
+	start := p.pos
+	for p.pos < len(p.in) {
+		c := p.in[p.pos]
+		if !(('a' <= c && c <= 'z') || (p.pos > start && (c == '_' || ('0' <= c && c <= '9')))) {
+			break
+		}
+		p.pos++
+	}
+	name := p.in[start:p.pos]
+	fn, ok := queryFunctions[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown function %q", name)
+	}
+	if !p.consume("(") {
+		return nil, p.errorf("expected '('")
+	}
+	expr := functionExpr{name: name}
+	p.skipBlank()
+	for p.peek() != ')' {
+		if len(expr.args) > 0 {
+			if !p.consume(",") {
+				return nil, p.errorf("expected ',' or ')'")
+			}
+			p.skipBlank()
+		}
+		argStart := p.pos
+		arg, err := p.parseComparable()
+		if err != nil {
+			return nil, err
+		}
+		if i := len(expr.args); i < len(fn.params) {
+			if err := p.checkArgument(name, fn.params[i], arg, argStart); err != nil {
+				return nil, err
+			}
+		}
+		expr.args = append(expr.args, arg)
+		p.skipBlank()
+	}
+	p.pos++
+	if len(expr.args) != len(fn.params) {
+		p.pos = start
+		return nil, p.errorf("%s() requires %d arguments", name, len(fn.params))
+	}
+	return expr, nil
+}
+
+// checkArgument checks that arg, which was parsed at offset start,
+// is well-typed for a function parameter of type want.
+func (p *queryParser) checkArgument(name string, want functionType, arg comparableExpr, start int) error {
+	pos := p.pos
+	defer func() { p.pos = pos }()
+	p.pos = start
+	switch arg := arg.(type) {
+	case pathQuery:
+		if want == valueType && !arg.isSingular() {
+			return p.errorf("argument to %s() must be a singular query", name)
+		}
+	case functionExpr:
+		if got := queryFunctions[arg.name].result; got != want {
+			return p.errorf("argument to %s() has wrong type", name)
+		}
+	default:
+		if want != valueType {
+			return p.errorf("argument to %s() must be a query", name)
+		}
+	}
+	return nil
+}
+
+// queryEvaluator evaluates a parsed query against a root value.
+type queryEvaluator struct {
+	root         *Value
+	withPointers bool
+	regexps      map[string]*regexp.Regexp
+}
+
+func (e *queryEvaluator) evalSegments(nodes []queryNode, segs []pathSegment) []queryNode {
+	for _, seg := range segs {
+		var out []queryNode
+		for _, n := range nodes {
+			if seg.descendant {
+				out = e.evalDescendant(out, n, seg.selectors)
+			} else {
+				out = e.evalSelectors(out, n, seg.selectors)
+			}
+		}
+		nodes = out
+	}
+	return nodes
+}
+
+// evalDescendant applies the selectors to n and to all of its descendants
+// in document order.
+func (e *queryEvaluator) evalDescendant(out []queryNode, n queryNode, sels []pathSelector) []queryNode {
+	out = e.evalSelectors(out, n, sels)
+	e.rangeChildren(n, func(_ string, child queryNode) {
+		out = e.evalDescendant(out, child, sels)
+	})
+	return out
+}
+
+// rangeChildren calls f for each member or element of n.
+// The name is only populated for object members.
+func (e *queryEvaluator) rangeChildren(n queryNode, f func(name string, child queryNode)) {
+	switch comp := n.value.Value.(type) {
+	case *Object:
+		for i := range comp.Members {
+			m := &comp.Members[i]
+			name := m.Name.Value.(Literal).memberName()
+			f(name, e.child(n, &m.Value, name))
+		}
+	case *Array:
+		for i := range comp.Elements {
+			f("", e.child(n, &comp.Elements[i], strconv.Itoa(i)))
+		}
+	}
+}
+
+func (e *queryEvaluator) child(parent queryNode, v *Value, token string) queryNode {
+	if !e.withPointers {
+		return queryNode{value: v}
+	}
+	return queryNode{value: v, pointer: parent.pointer + "/" + escapePointerToken(token)}
+}
+
+func (e *queryEvaluator) evalSelectors(out []queryNode, n queryNode, sels []pathSelector) []queryNode {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case nameSelector:
+			if obj, ok := n.value.Value.(*Object); ok {
+				for i := range obj.Members {
+					if obj.Members[i].Name.Value.(Literal).equalString(string(sel)) {
+						out = append(out, e.child(n, &obj.Members[i].Value, string(sel)))
+						break
+					}
+				}
+			}
+		case wildcardSelector:
+			e.rangeChildren(n, func(_ string, child queryNode) {
+				out = append(out, child)
+			})
+		case indexSelector:
+			if arr, ok := n.value.Value.(*Array); ok {
+				i := int64(sel)
+				if i < 0 {
+					i += int64(len(arr.Elements))
+				}
+				if 0 <= i && i < int64(len(arr.Elements)) {
+					out = append(out, e.child(n, &arr.Elements[i], strconv.FormatInt(i, 10)))
+				}
+			}
+		case sliceSelector:
+			if arr, ok := n.value.Value.(*Array); ok {
+				for _, i := range sel.indexes(int64(len(arr.Elements))) {
+					out = append(out, e.child(n, &arr.Elements[i], strconv.FormatInt(i, 10)))
+				}
+			}
+		case filterSelector:
+			e.rangeChildren(n, func(_ string, child queryNode) {
+				if e.evalLogical(sel.expr, child.value) {
+					out = append(out, child)
+				}
+			})
+		}
+	}
+	return out
+}
+
+// indexes returns the indexes selected from an array of length n
+// per RFC 9535, section 2.3.4.2.2.
+func (sel sliceSelector) indexes(n int64) (idxs []int64) {
+	normalize := func(i int64) int64 {
+		if i < 0 {
+			return n + i
+		}
+		return i
+	}
+	clamp := func(i, lo, hi int64) int64 {
+		if i < lo {
+			return lo
+		}
+		if i > hi {
+			return hi
+		}
+		return i
+	}
+	switch {
+	case sel.step > 0:
+		start, end := int64(0), n
+		if sel.hasStart {
+			start = normalize(sel.start)
+		}
+		if sel.hasEnd {
+			end = normalize(sel.end)
+		}
+		for i := clamp(start, 0, n); i < clamp(end, 0, n); i += sel.step {
+			idxs = append(idxs, i)
+		}
+	case sel.step < 0:
+		start, end := n-1, -n-1
+		if sel.hasStart {
+			start = normalize(sel.start)
+		}
+		if sel.hasEnd {
+			end = normalize(sel.end)
+		}
+		for i := clamp(start, -1, n-1); clamp(end, -1, n-1) < i; i += sel.step {
+			idxs = append(idxs, i)
+		}
+	}
+	return idxs
+}
+
+func (e *queryEvaluator) evalQuery(q pathQuery, current *Value) []queryNode {
+	start := e.root
+	if q.relative {
+		start = current
+	}
+	return e.evalSegments([]queryNode{{value: start}}, q.segments)
+}
+
+func (e *queryEvaluator) evalLogical(expr logicalExpr, current *Value) bool {
+	switch expr := expr.(type) {
+	case orExpr:
+		for _, x := range expr {
+			if e.evalLogical(x, current) {
+				return true
+			}
+		}
+		return false
+	case andExpr:
+		for _, x := range expr {
+			if !e.evalLogical(x, current) {
+				return false
+			}
+		}
+		return true
+	case notExpr:
+		return !e.evalLogical(expr.expr, current)
+	case existsExpr:
+		return len(e.evalQuery(expr.query, current)) > 0
+	case functionExpr:
+		switch r := e.evalFunction(expr, current).(type) {
+		case bool:
+			return r
+		case []queryNode:
+			return len(r) > 0
+		}
+		return false
+	case comparisonExpr:
+		x := e.evalComparable(expr.left, current)
+		y := e.evalComparable(expr.right, current)
+		switch expr.op {
+		case "==":
+			return equalJSON(x, y)
+		case "!=":
+			return !equalJSON(x, y)
+		case "<":
+			return lessJSON(x, y)
+		case "<=":
+			return lessJSON(x, y) || equalJSON(x, y)
+		case ">":
+			return lessJSON(y, x)
+		case ">=":
+			return lessJSON(y, x) || equalJSON(x, y)
+		}
+	}
+	return false
+}
+
+// nothing represents the absence of a value in a filter expression.
+type nothing struct{}
+
+func (e *queryEvaluator) evalComparable(expr comparableExpr, current *Value) interface{} {
+	switch expr := expr.(type) {
+	case literalExpr:
+		return expr.value
+	case pathQuery:
+		if nodes := e.evalQuery(expr, current); len(nodes) == 1 {
+			return decodeJSON(nodes[0].value)
+		}
+	case functionExpr:
+		return e.evalFunction(expr, current)
+	}
+	return nothing{}
+}
+
+// evalFunction returns a JSON value or nothing for functions with
+// a value result, a bool for functions with a logical result,
+// and []queryNode for functions with a nodes result.
+func (e *queryEvaluator) evalFunction(expr functionExpr, current *Value) interface{} {
+	var args []interface{}
+	for i, arg := range expr.args {
+		if q, ok := arg.(pathQuery); ok && queryFunctions[expr.name].params[i] == nodesType {
+			args = append(args, e.evalQuery(q, current))
+		} else {
+			args = append(args, e.evalComparable(arg, current))
+		}
+	}
+	switch expr.name {
+	case "length":
+		switch v := args[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(v))
+		case []interface{}:
+			return float64(len(v))
+		case map[string]interface{}:
+			return float64(len(v))
+		}
+		return nothing{}
+	case "count":
+		return float64(len(args[0].([]queryNode)))
+	case "match", "search":
+		s, ok1 := args[0].(string)
+		pattern, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return false
+		}
+		re := e.compileRegexp(pattern, expr.name == "match")
+		return re != nil && re.MatchString(s)
+	case "value":
+		if nodes := args[0].([]queryNode); len(nodes) == 1 {
+			return decodeJSON(nodes[0].value)
+		}
+		return nothing{}
+	}
+	return nothing{}
+}
+
+// compileRegexp compiles an I-Regexp (see RFC 9485) pattern,
+// returning nil if it is invalid.
+func (e *queryEvaluator) compileRegexp(pattern string, full bool) *regexp.Regexp {
+	key := strconv.FormatBool(full) + pattern
+	if re, ok := e.regexps[key]; ok {
+		return re
+	}
+	var re *regexp.Regexp
+	if s, ok := translateIRegexp(pattern); ok {
+		if full {
+			s = `\A(?:` + s + `)\z`
+		}
+		re, _ = regexp.Compile(s)
+	}
+	if e.regexps == nil {
+		e.regexps = make(map[string]*regexp.Regexp)
+	}
+	e.regexps[key] = re
+	return re
+}
+
+// translateIRegexp translates an I-Regexp pattern into RE2 syntax.
+// It reports false if the pattern uses syntax not permitted by I-Regexp.
+func translateIRegexp(pattern string) (string, bool) {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(pattern) {
+				return "", false
+			}
+			i++
+			switch c := pattern[i]; {
+			case strings.IndexByte(`()*+-.?[\]^{|}nrt`, c) >= 0:
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case c == 'p' || c == 'P':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			default:
+				return "", false
+			}
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+			b.WriteByte(c)
+		case c == '[':
+			inClass = true
+			b.WriteByte(c)
+			// A leading '^' negates the class and a subsequent ']' is literal.
+			if i+1 < len(pattern) && pattern[i+1] == '^' {
+				b.WriteByte('^')
+				i++
+			}
+		case c == '.':
+			b.WriteString(`[^\n\r]`)
+		case c == '^' || c == '$':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '(' && i+1 < len(pattern) && pattern[i+1] == '?':
+			return "", false
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), !inClass
+}
+
+// decodeJSON decodes v as nil, bool, float64, string, []interface{},
+// or map[string]interface{}. For duplicate object names,
+// the first member takes precedence.
+func decodeJSON(v *Value) interface{} {
+	switch v2 := v.Value.(type) {
+	case Literal:
+		switch v2.Kind() {
+		case 'n':
+			return nil
+		case 't', 'f':
+			return v2.Bool()
+		case '"':
+			return v2.String()
+		case '0':
+			return v2.Float()
+		}
+	case *Object:
+		m := make(map[string]interface{}, len(v2.Members))
+		for i := range v2.Members {
+			name := v2.Members[i].Name.Value.(Literal).memberName()
+			if _, ok := m[name]; !ok {
+				m[name] = decodeJSON(&v2.Members[i].Value)
+			}
+		}
+		return m
+	case *Array:
+		s := make([]interface{}, len(v2.Elements))
+		for i := range v2.Elements {
+			s[i] = decodeJSON(&v2.Elements[i])
+		}
+		return s
+	}
+	return nothing{}
+}
+
+// equalJSON reports whether x and y are equal per RFC 9535, section 2.3.5.2.2.
+func equalJSON(x, y interface{}) bool {
+	switch x := x.(type) {
+	case []interface{}:
+		y, ok := y.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalJSON(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		y, ok := y.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, vx := range x {
+			vy, ok := y[k]
+			if !ok || !equalJSON(vx, vy) {
+				return false
+			}
+		}
+		return true
+	default:
+		return x == y
+	}
+}
+
+// lessJSON reports whether x < y per RFC 9535, section 2.3.5.2.2,
+// which is only defined for pairs of numbers or pairs of strings.
+func lessJSON(x, y interface{}) bool {
+	switch x := x.(type) {
+	case float64:
+		y, ok := y.(float64)
+		return ok && x < y
+	case string:
+		y, ok := y.(string)
+		return ok && x < y
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// queryStore is the example document from RFC 9535, section 1.5.
+const queryStore = `{
+	// The store.
+	"store": {
+		"book": [
+			{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+			{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+			{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99},
+			{"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99},
+		],
+		"bicycle": {"color": "red", "price": 399},
+	},
+}`
+
+var testdataQuery = []struct {
+	in      string
+	expr    string
+	want    []string
+	wantErr bool
+}{
+	// Examples from RFC 9535, section 1.5.
+	{in: queryStore, expr: `$.store.book[*].author`, want: []string{"/store/book/0/author", "/store/book/1/author", "/store/book/2/author", "/store/book/3/author"}},
+	{in: queryStore, expr: `$..author`, want: []string{"/store/book/0/author", "/store/book/1/author", "/store/book/2/author", "/store/book/3/author"}},
+	{in: queryStore, expr: `$.store.*`, want: []string{"/store/book", "/store/bicycle"}},
+	{in: queryStore, expr: `$.store..price`, want: []string{"/store/book/0/price", "/store/book/1/price", "/store/book/2/price", "/store/book/3/price", "/store/bicycle/price"}},
+	{in: queryStore, expr: `$..book[2]`, want: []string{"/store/book/2"}},
+	{in: queryStore, expr: `$..book[2].author`, want: []string{"/store/book/2/author"}},
+	{in: queryStore, expr: `$..book[2].publisher`, want: nil},
+	{in: queryStore, expr: `$..book[-1]`, want: []string{"/store/book/3"}},
+	{in: queryStore, expr: `$..book[0,1]`, want: []string{"/store/book/0", "/store/book/1"}},
+	{in: queryStore, expr: `$..book[:2]`, want: []string{"/store/book/0", "/store/book/1"}},
+	{in: queryStore, expr: `$..book[?@.isbn]`, want: []string{"/store/book/2", "/store/book/3"}},
+	{in: queryStore, expr: `$..book[?@.price<10]`, want: []string{"/store/book/0", "/store/book/2"}},
+	{in: queryStore, expr: `$..book[?@.price < $.store.bicycle.price && @.category == 'fiction']`, want: []string{"/store/book/1", "/store/book/2", "/store/book/3"}},
+	{in: queryStore, expr: `$.store.book[?!(@.price >= 10 || @.isbn)].title`, want: []string{"/store/book/0/title"}},
+
+	// Selectors.
+	{in: `{"o": {"j j": {"k.k": 3}}, "'": {"@": 2}}`, expr: `$.o['j j']['k.k']`, want: []string{"/o/j j/k.k"}},
+	{in: `{"o": {"j j": {"k.k": 3}}, "'": {"@": 2}}`, expr: `$["'"]["@"]`, want: []string{"/'/@"}},
+	{in: `{"a/b": {"~": 1}}`, expr: `$['a/b']['~']`, want: []string{"/a~1b/~0"}},
+	{in: `{"☺": 1}`, expr: `$['☺']`, want: []string{"/☺"}},
+	{in: `{"😀": 1}`, expr: `$["😀"]`, want: []string{"/😀"}},
+	{in: `{"😀": 1}`, expr: `$.😀`, want: []string{"/😀"}},
+	{in: `{unquoted: 1}`, expr: `$.unquoted`, want: []string{"/unquoted"}},
+	{in: `{"a": 1, "a": 2}`, expr: `$.a`, want: []string{"/a"}},
+	{in: `["a", "b"]`, expr: `$[1]`, want: []string{"/1"}},
+	{in: `["a", "b"]`, expr: `$[-2]`, want: []string{"/0"}},
+	{in: `["a", "b"]`, expr: `$[2]`, want: nil},
+	{in: `["a", "b"]`, expr: `$[0, 0]`, want: []string{"/0", "/0"}},
+	{in: `["a", "b", "c", "d", "e", "f", "g"]`, expr: `$[1:3]`, want: []string{"/1", "/2"}},
+	{in: `["a", "b", "c", "d", "e", "f", "g"]`, expr: `$[5:]`, want: []string{"/5", "/6"}},
+	{in: `["a", "b", "c", "d", "e", "f", "g"]`, expr: `$[1:5:2]`, want: []string{"/1", "/3"}},
+	{in: `["a", "b", "c", "d", "e", "f", "g"]`, expr: `$[5:1:-2]`, want: []string{"/5", "/3"}},
+	{in: `["a", "b", "c", "d", "e", "f", "g"]`, expr: `$[::-1]`, want: []string{"/6", "/5", "/4", "/3", "/2", "/1", "/0"}},
+	{in: `["a", "b", "c"]`, expr: `$[::0]`, want: nil},
+	{in: `["a", "b", "c"]`, expr: `$[-10:10]`, want: []string{"/0", "/1", "/2"}},
+	{in: `{"a": 1}`, expr: `$[0]`, want: nil},
+	{in: `[1]`, expr: `$.a`, want: nil},
+	{in: `1`, expr: `$`, want: []string{""}},
+
+	// Descendant segments (RFC 9535, section 2.5.2.3).
+	{in: `{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`, expr: `$..j`, want: []string{"/o/j", "/a/2/0/j"}},
+	{in: `{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`, expr: `$..[0]`, want: []string{"/a/0", "/a/2/0"}},
+	{in: `{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`, expr: `$.a..*`, want: []string{"/a/0", "/a/1", "/a/2", "/a/2/0", "/a/2/1", "/a/2/0/j", "/a/2/1/k"}},
+	{in: `{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`, expr: `$.o..[*, *]`, want: []string{"/o/j", "/o/k", "/o/j", "/o/k"}},
+
+	// Filter expressions (RFC 9535, section 2.3.5.3).
+	{in: `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]}`, expr: `$.a[?@.b == 'kilo']`, want: []string{"/a/9"}},
+	{in: `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]}`, expr: `$.a[?(@.b == 'kilo')]`, want: []string{"/a/9"}},
+	{in: `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]}`, expr: `$.a[?@>3.5]`, want: []string{"/a/1", "/a/4", "/a/5"}},
+	{in: `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]}`, expr: `$.a[?@.b]`, want: []string{"/a/6", "/a/7", "/a/8", "/a/9"}},
+	{in: `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]}`, expr: `$[?@.*]`, want: []string{"/a"}},
+	{in: `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]}`, expr: `$[?@[?@.b]]`, want: []string{"/a"}},
+	{in: `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]}`, expr: `$.a[?@<2 || @.b == "k"]`, want: []string{"/a/2", "/a/7"}},
+	{in: `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]}`, expr: `$.a[?match(@.b, "[jk]")]`, want: []string{"/a/6", "/a/7"}},
+	{in: `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]}`, expr: `$.a[?search(@.b, "[jk]")]`, want: []string{"/a/6", "/a/7", "/a/9"}},
+	{in: `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]}`, expr: `$.a[?@.b == $.x]`, want: []string{"/a/0", "/a/1", "/a/2", "/a/3", "/a/4", "/a/5"}},
+	{in: `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]}`, expr: `$.a[?@ == @]`, want: []string{"/a/0", "/a/1", "/a/2", "/a/3", "/a/4", "/a/5", "/a/6", "/a/7", "/a/8", "/a/9"}},
+	{in: `{"o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`, expr: `$.o[?@<3, ?@<3]`, want: []string{"/o/p", "/o/q", "/o/p", "/o/q"}},
+	{in: `{"o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`, expr: `$.o[?@>1 && @<4]`, want: []string{"/o/q", "/o/r"}},
+	{in: `{"o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`, expr: `$.o[?@.u || @.x]`, want: []string{"/o/t"}},
+	{in: `{"o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`, expr: `$[?@ == $.e]`, want: []string{"/e"}},
+	{in: `[{"a": [1, 2]}, {"a": [1, 2.0]}, {"a": [2, 1]}, {"a": {"x": null}}]`, expr: `$[?@.a == $[0].a]`, want: []string{"/0", "/1"}},
+	{in: `[{"a": {"x": null}}, {"a": {"x": null, "y": 1}}]`, expr: `$[?@.a == $[0].a]`, want: []string{"/0"}},
+	{in: `[1, "1", true, null, "b", "a"]`, expr: `$[?@ <= "a"]`, want: []string{"/1", "/5"}},
+	{in: `[1, "1", true, null, false]`, expr: `$[?@ == true]`, want: []string{"/2"}},
+	{in: `[1, "1", true, null, false]`, expr: `$[?@ == null]`, want: []string{"/3"}},
+	{in: `[1, "1", true, null, false]`, expr: `$[?@ != null]`, want: []string{"/0", "/1", "/2", "/4"}},
+	{in: `[1, 1e1, -0.5, 100]`, expr: `$[?@ >= 1.0E1]`, want: []string{"/1", "/3"}},
+
+	// Functions (RFC 9535, section 2.4).
+	{in: `["ab", "abc", [1, 2, 3], {"a": 1, "b": 2, "c": 3}, 3, "☺☺☺"]`, expr: `$[?length(@) == 3]`, want: []string{"/1", "/2", "/3", "/5"}},
+	{in: `[{"a": [1]}, {"a": [1, 2]}, {"b": 1}]`, expr: `$[?count(@.a[*]) == 2]`, want: []string{"/1"}},
+	{in: `[{"a": [1]}, {"a": [1, 2]}, {"b": 1}]`, expr: `$[?count(@..*) > 2]`, want: []string{"/1"}},
+	{in: `[{"a": [1]}, {"a": [7]}, {"a": [7, 7]}]`, expr: `$[?value(@.a[*]) == 7]`, want: []string{"/1"}},
+	{in: `[{"a": [1]}, {"a": [1, 2]}]`, expr: `$[?length(value(@.a[*])) == 1]`, want: nil},
+	{in: `["a.b", "a\nb", "a\rb", "ab"]`, expr: `$[?match(@, "a.b")]`, want: []string{"/0"}},
+	{in: `["2024-01-02", "x2024-01-02", "^a$"]`, expr: `$[?match(@, "[0-9]{4}-[0-9]{2}-[0-9]{2}")]`, want: []string{"/0"}},
+	{in: `["2024-01-02", "x2024-01-02", "^a$"]`, expr: `$[?search(@, "^a$")]`, want: []string{"/2"}},
+	{in: `["abc"]`, expr: `$[?match(@, "\\d")]`, want: nil},
+	{in: `["abc"]`, expr: `$[?match(@, "(?i)ABC")]`, want: nil},
+	{in: `["abc"]`, expr: `$[?match(@, "[")]`, want: nil},
+
+	// Whitespace.
+	{in: `{"a": [{"b": 1}]}`, expr: "$ .a [ 0 ] \n.b", want: []string{"/a/0/b"}},
+	{in: `{"a": [1, 2]}`, expr: "$.a[ ? @ > 1 ]", want: []string{"/a/1"}},
+
+	// Syntax and type errors.
+	{expr: ``, wantErr: true},
+	{expr: `@`, wantErr: true},
+	{expr: `$ `, wantErr: true},
+	{expr: `$.`, wantErr: true},
+	{expr: `$. a`, wantErr: true},
+	{expr: `$.1`, wantErr: true},
+	{expr: `$..`, wantErr: true},
+	{expr: `$[]`, wantErr: true},
+	{expr: `$[0`, wantErr: true},
+	{expr: `$[01]`, wantErr: true},
+	{expr: `$[-0]`, wantErr: true},
+	{expr: `$[9007199254740992]`, wantErr: true},
+	{expr: `$['a]`, wantErr: true},
+	{expr: `$['\"']`, wantErr: true},
+	{expr: `$['\uD800']`, wantErr: true},
+	{expr: `$['\q']`, wantErr: true},
+	{expr: `$[?@ == 01]`, wantErr: true},
+	{expr: `$[?1]`, wantErr: true},
+	{expr: `$[?@.* == 1]`, wantErr: true},
+	{expr: `$[?@..a == 1]`, wantErr: true},
+	{expr: `$[?length(@)]`, wantErr: true},
+	{expr: `$[?length(@.*) == 1]`, wantErr: true},
+	{expr: `$[?count(1) == 1]`, wantErr: true},
+	{expr: `$[?match(@) == true]`, wantErr: true},
+	{expr: `$[?match(@, "a") == true]`, wantErr: true},
+	{expr: `$[?unknown(@)]`, wantErr: true},
+	{expr: `$[?!@ == 1]`, wantErr: true},
+	{expr: `$[?(@]`, wantErr: true},
+}
+
+func TestQuery(t *testing.T) {
+	for _, tt := range testdataQuery {
+		t.Run("", func(t *testing.T) {
+			in := tt.in
+			if in == "" {
+				in = "null"
+			}
+			v, err := Parse([]byte(in))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			got, err := v.QueryPaths(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("QueryPaths(%q) error = %v, want error %v", tt.expr, err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("QueryPaths(%q) mismatch (-want +got):\n%s", tt.expr, diff)
+			}
+
+			// Query must return the same values as finding each pointer.
+			vals, err := v.Query(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Query(%q) error = %v, want error %v", tt.expr, err, tt.wantErr)
+			}
+			if len(vals) != len(got) {
+				t.Fatalf("Query(%q) returned %d values, want %d", tt.expr, len(vals), len(got))
+			}
+			for i, ptr := range got {
+				if vals[i] != v.Find(ptr) {
+					t.Errorf("Query(%q)[%d] does not point to %q", tt.expr, i, ptr)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryEdit(t *testing.T) {
+	v, err := Parse([]byte(queryStore))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	vals, err := v.Query(`$.store..price`)
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	for _, val := range vals {
+		val.Value = Float(val.Value.(Literal).Float() * 2)
+	}
+	got := v.String()
+	want, err := Parse([]byte(queryStore))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if err := want.Patch([]byte(`[
+		{"op": "replace", "path": "/store/book/0/price", "value": 17.9},
+		{"op": "replace", "path": "/store/book/1/price", "value": 25.98},
+		{"op": "replace", "path": "/store/book/2/price", "value": 17.98},
+		{"op": "replace", "path": "/store/book/3/price", "value": 45.98},
+		{"op": "replace", "path": "/store/bicycle/price", "value": 798},
+	]`)); err != nil {
+		t.Fatalf("Patch error: %v", err)
+	}
+	if diff := cmp.Diff(want.String(), got); diff != "" {
+		t.Errorf("edited value mismatch (-want +got):\n%s", diff)
+	}
+}
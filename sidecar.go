@@ -0,0 +1,176 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CommentMap maps JSON pointers to the comments associated with each value.
+// It can be marshaled as JSON to serve as a sidecar file for comments
+// that cannot be represented within a JSON document.
+type CommentMap map[string]CommentGroup
+
+// CommentsToMembers converts comments into object members so that they
+// survive a round trip through tools that only accept standard JSON.
+// The leading comments of each object member are converted into a member
+// with the given key that precedes it (e.g., {"//": "comment", "name": ...}),
+// whose value is a string with the text of each comment on its own line.
+// All other comments associated with a value (see Comments) are returned
+// in a CommentMap. All comments are removed from v.
+// Comments that are not associated with any value are discarded,
+// as are the non-leading comments within or around an object member
+// that has the same name as an earlier member.
+//
+// Use MembersToComments to restore the comments.
+// It does not otherwise standardize the value.
+// Use Standardize or Minimize to obtain standard JSON.
+func (v *Value) CommentsToMembers(key string) CommentMap {
+	type insertion struct {
+		obj  *Object
+		i    int
+		text string
+	}
+	var insertions []insertion
+	var m CommentMap
+	record := func(ptr string, g CommentGroup) {
+		for _, cs := range [][]Comment{g.Leading, g.Trailing} {
+			for i := range cs {
+				cs[i].Offset = 0 // meaningless once v is modified
+			}
+		}
+		if len(g.Leading) > 0 || len(g.Trailing) > 0 {
+			if m == nil {
+				m = make(CommentMap)
+			}
+			m[ptr] = g
+		}
+	}
+
+	v.UpdateOffsets()
+	record("", v.Comments(""))
+	// Comments of values shadowed by an earlier member with the same name
+	// are not recorded since their pointers resolve to the earlier member.
+	var walk func(node *Value, p Pointer, shadowed bool)
+	walk = func(node *Value, p Pointer, shadowed bool) {
+		switch comp := node.Value.(type) {
+		case *Object:
+			seen := make(map[string]bool, len(comp.Members))
+			for i := range comp.Members {
+				name := comp.Members[i].Name.Value.(Literal).memberName()
+				p2 := p.Append(name)
+				shadowed2 := shadowed || seen[name]
+				seen[name] = true
+				g := node.ChildComments(i)
+				if len(g.Leading) > 0 {
+					var lines []string
+					for _, c := range g.Leading {
+						lines = append(lines, c.Text)
+					}
+					insertions = append(insertions, insertion{comp, i, strings.Join(lines, "\n")})
+					g.Leading = nil
+				}
+				if !shadowed2 {
+					record(p2.String(), g)
+				}
+				walk(&comp.Members[i].Value, p2, shadowed2)
+			}
+		case *Array:
+			for i := range comp.Elements {
+				p2 := p.Append(strconv.Itoa(i))
+				if !shadowed {
+					record(p2.String(), node.ChildComments(i))
+				}
+				walk(&comp.Elements[i], p2, shadowed)
+			}
+		}
+	}
+	walk(v, Pointer{}, false)
+
+	v.RewriteComments(func(Comment) (string, bool) { return "", false })
+	for j := len(insertions) - 1; j >= 0; j-- {
+		ins := insertions[j]
+		insertPreservingComma(ins.obj, ins.i, Value{Value: String(ins.text)})
+		ins.obj.Members[ins.i].Name.Value = ins.obj.memberNameLiteral(key)
+	}
+	v.UpdateOffsets()
+	return m
+}
+
+// MembersToComments is the inverse of CommentsToMembers.
+// Every object member with the given key is removed and its string value
+// is converted into line comments that precede the next member.
+// The comments in m are then restored to the values that still exist,
+// where values that no longer exist are ignored.
+//
+// It reports an error if a member with the given key has a non-string value,
+// in which case v is left partially mutated.
+// It does not format the value. It is recommended that Format be called after.
+func (v *Value) MembersToComments(key string, m CommentMap) error {
+	var err error
+	v.Range(func(node *Value) bool {
+		obj, ok := node.Value.(*Object)
+		if !ok {
+			return true
+		}
+		var pending []Comment
+		for i := 0; i < len(obj.Members); {
+			if !obj.Members[i].Name.Value.(Literal).equalString(key) {
+				if len(pending) > 0 {
+					leading, _ := formatComments(pending, "\n")
+					obj.beforeExtraAt(i).injectLeadingComments(leading)
+					pending = nil
+				}
+				i++
+				continue
+			}
+			lit, ok := obj.Members[i].Value.Value.(Literal)
+			if !ok || lit.Kind() != '"' {
+				ptr, _ := v.PathTo(&obj.Members[i].Value)
+				err = fmt.Errorf("hujson: comment member %q has a non-string value", ptr)
+				return false
+			}
+			for _, line := range strings.Split(lit.String(), "\n") {
+				pending = append(pending, Comment{Text: strings.TrimSpace(line), Style: LineComment})
+			}
+			removePreservingComma(obj, i)
+		}
+		if len(pending) > 0 {
+			leading, _ := formatComments(pending, "\n")
+			obj.beforeExtraAt(len(obj.Members)).injectLeadingComments(leading)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	ptrs := make([]string, 0, len(m))
+	for ptr := range m {
+		ptrs = append(ptrs, ptr)
+	}
+	sort.Strings(ptrs)
+	for _, ptr := range ptrs {
+		if v.Find(ptr) == nil {
+			continue
+		}
+		g := m[ptr]
+		existing := v.Comments(ptr)
+		if len(g.Leading) == 0 {
+			g.Leading = existing.Leading
+		}
+		if len(g.Trailing) == 0 {
+			g.Trailing = existing.Trailing
+		}
+		if err := v.SetComments(ptr, g); err != nil {
+			return err
+		}
+	}
+	v.UpdateOffsets()
+	return nil
+}
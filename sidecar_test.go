@@ -0,0 +1,151 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCommentsToMembers(t *testing.T) {
+	const in = `// Root comment.
+{
+	// The name.
+	// Must be unique.
+	"name": "x",
+	"port": 80, // Trailing comment.
+	"hosts": [
+		// First host.
+		"a",
+		"b",
+	],
+	"tls": {
+		/* Certificate path. */
+		"cert": null,
+	},
+}
+`
+	v, err := Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	m := v.CommentsToMembers("//")
+	v.Standardize()
+	v.Format()
+	gotJSON := v.String()
+	wantJSON := `{
+	"//":   "The name.\nMust be unique.",
+	"name": "x",
+	"port": 80,
+	"hosts": [
+		"a",
+		"b"
+	],
+	"tls": {
+		"//":   "Certificate path.",
+		"cert": null
+	}
+}
+`
+	if diff := cmp.Diff(wantJSON, gotJSON); diff != "" {
+		t.Errorf("CommentsToMembers mismatch (-want +got):\n%s\n\ngot:\n%s", diff, gotJSON)
+	}
+
+	// Round trip the sidecar comments through JSON.
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	wantSidecar := `{"":{"leading":[{"text":"Root comment.","style":"line"}]},` +
+		`"/hosts/0":{"leading":[{"text":"First host.","style":"line"}]},` +
+		`"/port":{"trailing":[{"text":"Trailing comment.","style":"line"}]}}`
+	if diff := cmp.Diff(wantSidecar, string(b)); diff != "" {
+		t.Errorf("sidecar mismatch (-want +got):\n%s", diff)
+	}
+	var m2 CommentMap
+	if err := json.Unmarshal(b, &m2); err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+
+	// Edit the JSON and restore the comments.
+	v2, err := Parse([]byte(gotJSON))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if err := v2.Set("/port", 443); err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+	if err := v2.MembersToComments("//", m2); err != nil {
+		t.Fatalf("MembersToComments error: %v", err)
+	}
+	v2.Format()
+	got := v2.String()
+	want := `// Root comment.
+{
+	// The name.
+	// Must be unique.
+	"name": "x",
+	"port": 443, // Trailing comment.
+	"hosts": [
+		// First host.
+		"a",
+		"b",
+	],
+	"tls": {
+		// Certificate path.
+		"cert": null,
+	},
+}
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MembersToComments mismatch (-want +got):\n%s\n\ngot:\n%s", diff, got)
+	}
+
+	v3, _ := Parse([]byte(`{"//": 1}`))
+	if err := v3.MembersToComments("//", nil); err == nil {
+		t.Errorf("MembersToComments succeeded, want error")
+	}
+}
+
+func TestCommentsToMembersDuplicateNames(t *testing.T) {
+	const in = `{
+	// First.
+	"a": 1, // one
+	// Second.
+	"a": [
+		// Shadowed.
+		2,
+	], // two
+}
+`
+	v, err := Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	m := v.CommentsToMembers("//")
+	wantMap := CommentMap{"/a": {Trailing: []Comment{{Text: "one", Style: LineComment}}}}
+	if diff := cmp.Diff(wantMap, m); diff != "" {
+		t.Errorf("CommentsToMembers map mismatch (-want +got):\n%s", diff)
+	}
+	if err := v.MembersToComments("//", m); err != nil {
+		t.Fatalf("MembersToComments error: %v", err)
+	}
+	v.Format()
+	got := v.String()
+	want := `{
+	// First.
+	"a": 1, // one
+	// Second.
+	"a": [
+		2,
+	],
+}
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MembersToComments mismatch (-want +got):\n%s\n\ngot:\n%s", diff, got)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// SourceMap translates offsets in the output of StandardizeWithSourceMap
+// back to offsets in the original HuJSON input.
+type SourceMap struct {
+	input      []byte
+	insertions []int // sorted input offsets before which a byte was inserted
+}
+
+// StandardizeWithSourceMap is like Standardize, but also returns a SourceMap
+// for translating offsets in the standardized output back to the input.
+// Standardize preserves offsets except that quoting an unquoted key
+// inserts a quote before and after the key.
+func (v *Value) StandardizeWithSourceMap() *SourceMap {
+	v.UpdateOffsets()
+	m := &SourceMap{input: v.Pack()}
+	v.Range(func(v *Value) bool {
+		if obj, ok := v.Value.(*Object); ok {
+			for _, mem := range obj.Members {
+				if mem.Name.Value.isUnquotedKey() {
+					m.insertions = append(m.insertions, mem.Name.StartOffset, mem.Name.EndOffset)
+				}
+			}
+		}
+		return true
+	})
+	sort.Ints(m.insertions)
+	v.Standardize()
+	return m
+}
+
+// StandardizeWithSourceMap is like Standardize, but also returns a SourceMap
+// for translating offsets in the standardized output back to b.
+// If an error is encountered, then b is returned as is along with the error.
+func StandardizeWithSourceMap(b []byte) ([]byte, *SourceMap, error) {
+	ast, err := Parse(b)
+	if err != nil {
+		return b, nil, err
+	}
+	m := ast.StandardizeWithSourceMap()
+	return ast.Pack(), m, nil
+}
+
+// Offset translates an offset in the standardized output
+// to the corresponding offset in the input.
+// An inserted quote maps to the offset it was inserted before.
+func (m *SourceMap) Offset(n int) int {
+	// The j-th inserted byte is at output offset m.insertions[j]+j.
+	j := sort.Search(len(m.insertions), func(j int) bool {
+		return m.insertions[j]+j >= n
+	})
+	if j < len(m.insertions) && m.insertions[j]+j == n {
+		return m.insertions[j]
+	}
+	return n - j
+}
+
+// Position translates an offset in the standardized output
+// to the corresponding 1-based line and column in the input,
+// where the column is measured in bytes.
+func (m *SourceMap) Position(n int) (line, column int) {
+	n = m.Offset(n)
+	if n < 0 {
+		n = 0
+	} else if n > len(m.input) {
+		n = len(m.input)
+	}
+	return lineColumn(m.input, n)
+}
+
+// TranslateError translates the offset in a *json.SyntaxError or
+// *json.UnmarshalTypeError within err (as reported by decoding the
+// standardized output) to the corresponding offset in the input.
+// The error is modified in place and returned.
+func (m *SourceMap) TranslateError(err error) error {
+	var serr *json.SyntaxError
+	if errors.As(err, &serr) {
+		serr.Offset = int64(m.Offset(int(serr.Offset)))
+	}
+	var terr *json.UnmarshalTypeError
+	if errors.As(err, &terr) {
+		terr.Offset = int64(m.Offset(int(terr.Offset)))
+	}
+	return err
+}
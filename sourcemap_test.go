@@ -0,0 +1,57 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSourceMap(t *testing.T) {
+	const in = "{\n\tname: \"x\",\n\tport: \"80\", // comment\n}"
+	out, m, err := StandardizeWithSourceMap([]byte(in))
+	if err != nil {
+		t.Fatalf("StandardizeWithSourceMap error: %v", err)
+	}
+	const want = "{\n\t\"name\": \"x\",\n\t\"port\": \"80\"            \n}"
+	if string(out) != want {
+		t.Fatalf("StandardizeWithSourceMap = %q, want %q", out, want)
+	}
+
+	// Every byte that is not an inserted quote or a replaced comment
+	// maps to an identical byte.
+	inserted := map[int]bool{3: true, 8: true, 17: true, 22: true}
+	for i := range out {
+		if j := m.Offset(i); !inserted[i] && out[i] != ' ' && out[i] != in[j] {
+			t.Errorf("Offset(%d) = %d: output byte %q does not match input byte %q", i, j, out[i], in[j])
+		}
+	}
+	if got := m.Offset(3); got != 3 {
+		t.Errorf("Offset(3) = %d, want 3", got)
+	}
+	if got := m.Offset(8); got != 7 {
+		t.Errorf("Offset(8) = %d, want 7", got)
+	}
+
+	var config struct{ Port int }
+	err = m.TranslateError(json.Unmarshal(out, &config))
+	var terr *json.UnmarshalTypeError
+	if !errors.As(err, &terr) {
+		t.Fatalf("Unmarshal error = %v, want *json.UnmarshalTypeError", err)
+	}
+	if line, column := m.Position(int(terr.Offset)); line != 3 || column != 8 {
+		t.Errorf("Position = %d:%d, want 3:8", line, column)
+	}
+
+	err = m.TranslateError(json.Unmarshal(out[:20], &config))
+	var serr *json.SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("Unmarshal error = %v, want *json.SyntaxError", err)
+	}
+	if serr.Offset != 17 {
+		t.Errorf("SyntaxError.Offset = %d, want 17", serr.Offset)
+	}
+}
@@ -4,6 +4,11 @@
 
 package hujson
 
+import (
+	"bytes"
+	"regexp"
+)
+
 // IsStandard reports whether this is standard JSON
 // by checking that there are no comments and no trailing commas.
 func (v Value) IsStandard() bool {
@@ -66,6 +71,8 @@ func (v *Value) minimize() bool {
 // making it compliant with standard JSON per RFC 8259.
 // All comments and trailing commas are replaced with a space character
 // in order to preserve the original line numbers and byte offsets.
+// However, quoting unquoted keys inserts two bytes per key.
+// Use StandardizeWithSourceMap to translate offsets back to the original.
 func (v *Value) Standardize() {
 	v.standardize()
 	v.UpdateOffsets() // should be noop if offsets are already correct
@@ -99,3 +106,129 @@ func (b *Extra) standardize() {
 		}
 	}
 }
+
+// MinimizeOptions configures the behavior of Value.MinimizeWithOptions.
+type MinimizeOptions struct {
+	// KeepBanners keeps block comments that start with "/*!",
+	// which conventionally mark license headers and other banners
+	// that must be retained by minifiers.
+	KeepBanners bool
+	// Keep keeps comments that match the regular expression,
+	// where the matched text includes the comment delimiters.
+	Keep *regexp.Regexp
+	// Standard requires the result to be standard JSON per RFC 8259,
+	// in which case kept comments are removed from the value
+	// (and returned instead) and unquoted keys are quoted.
+	Standard bool
+}
+
+// MinimizeWithOptions is like Minimize, but keeps the comments selected
+// by opts. Otherwise, kept comments remain in place with no whitespace
+// other than the newline that terminates a line comment
+// and a space that separates an unquoted key from a comment.
+// If opts.Standard is set, the kept comments are instead removed and
+// returned with their offsets in v prior to minimization.
+func (v *Value) MinimizeWithOptions(opts MinimizeOptions) []Comment {
+	v.UpdateOffsets()
+	var removed []Comment
+	v.rangeExtras(func(b *Extra, offset int) {
+		var kept Extra
+		forEachComment(*b, func(raw Extra, n int) {
+			if keepComment(raw, opts.KeepBanners, opts.Keep) {
+				if opts.Standard {
+					removed = append(removed, parseComments(raw, offset+n)...)
+				} else {
+					kept = append(kept, raw...)
+				}
+			}
+		})
+		*b = kept
+	})
+	v.Range(func(v *Value) bool {
+		if obj, ok := v.Value.(*Object); ok {
+			if opts.Standard {
+				obj.quoteUnquotedKeys()
+			}
+			for i := range obj.Members {
+				// An unquoted key must be separated from a kept comment.
+				name := &obj.Members[i].Name
+				if len(name.AfterExtra) > 0 && name.Value.(Literal).isUnquotedKey() {
+					name.AfterExtra = append(Extra(" "), name.AfterExtra...)
+				}
+			}
+		}
+		if comp, ok := v.Value.(composite); ok {
+			setTrailingComma(comp, false)
+		}
+		return true
+	})
+	v.UpdateOffsets()
+	return removed
+}
+
+// StandardizeOptions configures the behavior of Value.StandardizeWithOptions.
+type StandardizeOptions struct {
+	// KeepBanners keeps block comments that start with "/*!".
+	KeepBanners bool
+	// Keep keeps comments that match the regular expression,
+	// where the matched text includes the comment delimiters.
+	Keep *regexp.Regexp
+}
+
+// StandardizeWithOptions is like Standardize, but returns the comments
+// selected by opts, which cannot be kept in place in standard JSON.
+// The offset of each comment is its offset in v prior to standardization,
+// which only differs from the standardized output if v has unquoted keys
+// (see StandardizeWithSourceMap).
+func (v *Value) StandardizeWithOptions(opts StandardizeOptions) []Comment {
+	v.UpdateOffsets()
+	var removed []Comment
+	v.rangeExtras(func(b *Extra, offset int) {
+		forEachComment(*b, func(raw Extra, n int) {
+			if keepComment(raw, opts.KeepBanners, opts.Keep) {
+				removed = append(removed, parseComments(raw, offset+n)...)
+			}
+		})
+	})
+	v.Standardize()
+	return removed
+}
+
+// keepComment reports whether the raw comment is selected
+// by the KeepBanners and Keep options.
+func keepComment(raw Extra, banners bool, re *regexp.Regexp) bool {
+	return (banners && bytes.HasPrefix(raw, []byte("/*!"))) || (re != nil && re.Match(raw))
+}
+
+// forEachComment calls f for each comment in b with its offset in b.
+func forEachComment(b Extra, f func(comment Extra, n int)) {
+	for n := 0; len(b) > n; {
+		n += consumeWhitespace(b[n:])
+		nc := consumeComment(b[n:])
+		if nc <= 0 {
+			return
+		}
+		f(b[n:][:nc], n)
+		n += nc
+	}
+}
+
+// rangeExtras calls f for every Extra in v in document order
+// along with its offset, which is only accurate if the offsets are.
+func (v *Value) rangeExtras(f func(b *Extra, offset int)) {
+	f(&v.BeforeExtra, v.StartOffset-len(v.BeforeExtra))
+	switch v2 := v.Value.(type) {
+	case *Object:
+		for i := range v2.Members {
+			v2.Members[i].Name.rangeExtras(f)
+			v2.Members[i].Value.rangeExtras(f)
+		}
+		f(&v2.AfterExtra, v.EndOffset-len("}")-len(v2.AfterExtra))
+	case *Array:
+		for i := range v2.Elements {
+			v2.Elements[i].rangeExtras(f)
+		}
+		f(&v2.AfterExtra, v.EndOffset-len("]")-len(v2.AfterExtra))
+	}
+	f(&v.AfterExtra, v.EndOffset)
+}
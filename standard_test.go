@@ -0,0 +1,97 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testdataBanner = `/*! Copyright 2021 Example Inc. */
+// Regular comment.
+{
+	// @license MIT
+	a: 1, /* drop */
+	"b": [2, 3,], // trailing
+}
+`
+
+func TestMinimizeWithOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		opts        MinimizeOptions
+		want        string
+		wantRemoved []Comment
+	}{{
+		name: "None",
+		want: `{a:1,"b":[2,3]}`,
+	}, {
+		name: "KeepBanners",
+		opts: MinimizeOptions{KeepBanners: true},
+		want: `/*! Copyright 2021 Example Inc. */{a:1,"b":[2,3]}`,
+	}, {
+		name: "KeepPattern",
+		opts: MinimizeOptions{Keep: regexp.MustCompile(`@license|trailing`)},
+		want: "{// @license MIT\na:1,\"b\":[2,3]// trailing\n}",
+	}, {
+		name: "Standard",
+		opts: MinimizeOptions{KeepBanners: true, Keep: regexp.MustCompile(`@license`), Standard: true},
+		want: `{"a":1,"b":[2,3]}`,
+		wantRemoved: []Comment{
+			{"! Copyright 2021 Example Inc.", BlockComment, 0},
+			{"@license MIT", LineComment, 58},
+		},
+	}, {
+		name: "UnquotedKey",
+		in:   "{foo /*! x */: 1, bar: /*! y */ 2}",
+		opts: MinimizeOptions{KeepBanners: true},
+		want: "{foo /*! x */:1,bar:/*! y */2}",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := tt.in
+			if in == "" {
+				in = testdataBanner
+			}
+			v, err := Parse([]byte(in))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			removed := v.MinimizeWithOptions(tt.opts)
+			if got := string(v.Pack()); got != tt.want {
+				t.Errorf("MinimizeWithOptions = %q, want %q", got, tt.want)
+			}
+			if diff := cmp.Diff(tt.wantRemoved, removed); diff != "" {
+				t.Errorf("removed comments mismatch (-want +got):\n%s", diff)
+			}
+			if _, err := Parse(v.Pack()); err != nil {
+				t.Errorf("Parse error: %v", err)
+			}
+			for _, c := range removed {
+				if in[c.Offset:][:2] != c.String()[:2] {
+					t.Errorf("comment %q not found at offset %d", c.Text, c.Offset)
+				}
+			}
+		})
+	}
+}
+
+func TestStandardizeWithOptions(t *testing.T) {
+	v, err := Parse([]byte(testdataBanner))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	removed := v.StandardizeWithOptions(StandardizeOptions{KeepBanners: true})
+	want := []Comment{{"! Copyright 2021 Example Inc.", BlockComment, 0}}
+	if diff := cmp.Diff(want, removed); diff != "" {
+		t.Errorf("removed comments mismatch (-want +got):\n%s", diff)
+	}
+	if !v.IsStandard() {
+		t.Errorf("IsStandard = false, want true")
+	}
+}
@@ -30,6 +30,14 @@
 // The Format method formats the value; it is similar to `go fmt`,
 // but instead for the HuJSON and standard JSON format.
 // The Patch method applies a JSON Patch (RFC 6902) to the receiving value.
+// The PatchWithOptions method additionally permits HuJSON-specific
+// extensions to JSON Patch, such as operations for editing comments.
+//
+// Values within a HuJSON value can be located using the Find method,
+// which resolves a JSON Pointer (RFC 6901), or the Query method,
+// which evaluates a JSONPath expression (RFC 9535).
+// Both return pointers into the syntax tree such that the located values
+// may be modified in place with their surrounding comments intact.
 //
 // # Grammar
 //
@@ -91,8 +99,10 @@ package hujson
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
 	"unicode/utf8"
@@ -341,6 +351,217 @@ func (b Literal) Float() (n float64) {
 	return 0
 }
 
+// LiteralError reports that a literal could not be converted to a Go value.
+type LiteralError struct {
+	Literal Literal // the literal being converted
+	Type    string  // the Go type being converted to (e.g., "int64")
+	// Err is the reason the literal could not be converted.
+	// It is nil if the literal is of the wrong JSON kind,
+	// strconv.ErrRange if the number is out of range for Type,
+	// ErrNotInteger if the number is not an integer but Type is, or
+	// strconv.ErrSyntax if the literal is malformed.
+	Err error
+}
+
+func (e *LiteralError) Error() string {
+	var kind string
+	switch e.Literal.Kind() {
+	case 'n':
+		kind = "JSON null"
+	case 't', 'f':
+		kind = "JSON boolean"
+	case '"':
+		kind = "JSON string"
+	case '0':
+		kind = "JSON number"
+	default:
+		kind = "invalid literal"
+	}
+	if e.Err == nil {
+		return fmt.Sprintf("hujson: cannot convert %s %s to %s", kind, e.Literal, e.Type)
+	}
+	return fmt.Sprintf("hujson: cannot convert %s %s to %s: %v", kind, e.Literal, e.Type, e.Err)
+}
+
+func (e *LiteralError) Unwrap() error {
+	return e.Err
+}
+
+// ErrNotInteger reports that a JSON number with a fractional part
+// cannot be converted to an integer type.
+var ErrNotInteger = errors.New("not an integer")
+
+// number returns the literal as a string if it is a valid JSON number.
+func (b Literal) number(typ string) (string, error) {
+	if b.Kind() != '0' {
+		return "", &LiteralError{b, typ, nil}
+	}
+	if !b.IsValid() {
+		return "", &LiteralError{b, typ, strconv.ErrSyntax}
+	}
+	return string(b), nil
+}
+
+// Int64 returns the signed integer value for a JSON number.
+// Numbers with a fractional part or exponent are permitted
+// so long as they denote an integer (e.g., 1.0 or 1e3).
+func (b Literal) Int64() (int64, error) {
+	n, err := b.bigInt("int64")
+	if err != nil {
+		return 0, err
+	}
+	if !n.IsInt64() {
+		return 0, &LiteralError{b, "int64", strconv.ErrRange}
+	}
+	return n.Int64(), nil
+}
+
+// Uint64 returns the unsigned integer value for a JSON number.
+// Numbers with a fractional part or exponent are permitted
+// so long as they denote an integer (e.g., 1.0 or 1e3).
+func (b Literal) Uint64() (uint64, error) {
+	n, err := b.bigInt("uint64")
+	if err != nil {
+		return 0, err
+	}
+	if !n.IsUint64() {
+		return 0, &LiteralError{b, "uint64", strconv.ErrRange}
+	}
+	return n.Uint64(), nil
+}
+
+// Float64 returns the floating-point value for a JSON number,
+// rounded to the nearest representable value.
+// Unlike Float, JSON strings such as "NaN" are reported as errors.
+func (b Literal) Float64() (float64, error) {
+	s, err := b.number("float64")
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, &LiteralError{b, "float64", strconv.ErrRange}
+	}
+	return f, nil
+}
+
+// BigInt returns the exact integer value for a JSON number.
+// Numbers with a fractional part or exponent are permitted
+// so long as they denote an integer (e.g., 1.0 or 1e3).
+func (b Literal) BigInt() (*big.Int, error) {
+	return b.bigInt("*big.Int")
+}
+
+func (b Literal) bigInt(typ string) (*big.Int, error) {
+	s, err := b.number(typ)
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := new(big.Int).SetString(s, 10); ok {
+		return n, nil
+	}
+	// Avoid big.Rat, which rejects exponents beyond ±1e6 (e.g., 1e-1000000000),
+	// and avoid materializing huge integers for numbers like 1e1000000000.
+	n := normalizeNumber(b)
+	if n == "0" {
+		return new(big.Int), nil
+	}
+	i := strings.LastIndexByte(n, 'e')
+	exp, _ := new(big.Int).SetString(n[i+len("e"):], 10)
+	switch {
+	case exp.Sign() < 0: // trailing zeros are already removed from the digits
+		return nil, &LiteralError{b, typ, ErrNotInteger}
+	case exp.Cmp(big.NewInt(1e6)) > 0:
+		return nil, &LiteralError{b, typ, strconv.ErrRange}
+	}
+	digits, _ := new(big.Int).SetString(n[:i], 10)
+	return digits.Mul(digits, new(big.Int).Exp(big.NewInt(10), exp, nil)), nil
+}
+
+// BigFloat returns the value for a JSON number with a precision
+// sufficient to represent all of its significant decimal digits.
+func (b Literal) BigFloat() (*big.Float, error) {
+	s, err := b.number("*big.Float")
+	if err != nil {
+		return nil, err
+	}
+	prec := uint(4 * len(s)) // at least log2(10) bits per decimal digit
+	if prec < 64 {
+		prec = 64
+	}
+	f, _, err := big.ParseFloat(s, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, &LiteralError{b, "*big.Float", strconv.ErrRange}
+	}
+	return f, nil
+}
+
+// Number returns the raw representation of a JSON number.
+// It returns an empty string if the literal is not a valid JSON number.
+func (b Literal) Number() json.Number {
+	if s, err := b.number("json.Number"); err == nil {
+		return json.Number(s)
+	}
+	return ""
+}
+
+// Str returns the unescaped string value for a JSON string.
+// Unlike String, other JSON kinds are reported as errors,
+// as are strings with unpaired surrogate escapes (e.g., "\ud800"),
+// which String replaces with utf8.RuneError.
+func (b Literal) Str() (string, error) {
+	var s string
+	if b.Kind() != '"' {
+		return "", &LiteralError{b, "string", nil}
+	}
+	if json.Unmarshal(b, &s) != nil || !b.IsValid() || hasLoneSurrogate(b) {
+		return "", &LiteralError{b, "string", strconv.ErrSyntax}
+	}
+	return s, nil
+}
+
+// hasLoneSurrogate reports whether the string literal b has a \u escape
+// for a UTF-16 surrogate that is not part of a surrogate pair,
+// which json.Unmarshal silently replaces with utf8.RuneError.
+func hasLoneSurrogate(b Literal) bool {
+	hex := func(b []byte) rune {
+		if len(b) < len(`\uXXXX`) || b[1] != 'u' {
+			return -1
+		}
+		var r rune
+		for _, c := range b[2:6] {
+			switch {
+			case '0' <= c && c <= '9':
+				r = r<<4 | rune(c-'0')
+			case 'a' <= c && c <= 'f':
+				r = r<<4 | rune(c-'a'+10)
+			case 'A' <= c && c <= 'F':
+				r = r<<4 | rune(c-'A'+10)
+			default:
+				return -1
+			}
+		}
+		return r
+	}
+	for i := 0; i < len(b); i++ {
+		if b[i] != '\\' {
+			continue
+		}
+		switch r := hex(b[i:]); {
+		case 0xd800 <= r && r < 0xdc00:
+			if r2 := hex(b[i+len(`\uXXXX`):]); r2 < 0xdc00 || 0xe000 <= r2 {
+				return true
+			}
+			i += 2*len(`\uXXXX`) - 1
+		case 0xdc00 <= r && r < 0xe000:
+			return true
+		default:
+			i++ // skip the escaped character
+		}
+	}
+	return false
+}
+
 func (Literal) isValueTrimmed() {}
 
 // Object is an exact syntactic representation of a JSON object.
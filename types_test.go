@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestLiteralAccessors(t *testing.T) {
+	tests := []struct {
+		in      Literal
+		call    func(Literal) (interface{}, error)
+		want    interface{}
+		wantErr error // LiteralError.Err if !wantOK; nil for a kind mismatch
+		wantOK  bool
+	}{
+		{in: Literal("-123"), call: int64Of, want: int64(-123), wantOK: true},
+		{in: Literal("1e3"), call: int64Of, want: int64(1000), wantOK: true},
+		{in: Literal("1.50e1"), call: int64Of, want: int64(15), wantOK: true},
+		{in: Literal("1.5"), call: int64Of, wantErr: ErrNotInteger},
+		{in: Literal("9223372036854775808"), call: int64Of, wantErr: strconv.ErrRange},
+		{in: Literal("1e1000000000000"), call: int64Of, wantErr: strconv.ErrRange},
+		{in: Literal("1e-1000000000"), call: int64Of, wantErr: ErrNotInteger},
+		{in: Literal("-0.0e-1000000000"), call: int64Of, want: int64(0), wantOK: true},
+		{in: Literal("1000e-3"), call: int64Of, want: int64(1), wantOK: true},
+		{in: Literal(`"1"`), call: int64Of},
+		{in: Literal("01"), call: int64Of, wantErr: strconv.ErrSyntax},
+		{in: Literal("18446744073709551615"), call: uint64Of, want: uint64(18446744073709551615), wantOK: true},
+		{in: Literal("-1"), call: uint64Of, wantErr: strconv.ErrRange},
+		{in: Literal("null"), call: uint64Of},
+		{in: Literal("3.25"), call: float64Of, want: 3.25, wantOK: true},
+		{in: Literal("1e400"), call: float64Of, wantErr: strconv.ErrRange},
+		{in: Literal(`"NaN"`), call: float64Of},
+		{in: Literal("123456789012345678901234567890"), call: bigIntOf, want: "123456789012345678901234567890", wantOK: true},
+		{in: Literal("true"), call: bigIntOf},
+		{in: Literal("-12.5e1"), call: bigIntOf, want: "-125", wantOK: true},
+		{in: Literal("1e-1000000000"), call: bigIntOf, wantErr: ErrNotInteger},
+		{in: Literal("0.1000000000000000000000001"), call: bigFloatOf, want: "0.1000000000000000000000001", wantOK: true},
+		{in: Literal(`"hello\nworld"`), call: strOf, want: "hello\nworld", wantOK: true},
+		{in: Literal("5"), call: strOf},
+		{in: Literal(`"unterminated`), call: strOf, wantErr: strconv.ErrSyntax},
+		{in: Literal(`"\ud800"`), call: strOf, wantErr: strconv.ErrSyntax},
+		{in: Literal(`"\ud83d\ude00"`), call: strOf, want: "😀", wantOK: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.call(tt.in)
+		switch {
+		case tt.wantOK && err != nil:
+			t.Errorf("%s: unexpected error: %v", tt.in, err)
+		case tt.wantOK && got != tt.want:
+			t.Errorf("%s: got %v, want %v", tt.in, got, tt.want)
+		case !tt.wantOK:
+			var lerr *LiteralError
+			if !errors.As(err, &lerr) {
+				t.Errorf("%s: error = %v, want *LiteralError", tt.in, err)
+			} else if lerr.Err != tt.wantErr {
+				t.Errorf("%s: LiteralError.Err = %v, want %v", tt.in, lerr.Err, tt.wantErr)
+			}
+		}
+	}
+}
+
+func int64Of(b Literal) (interface{}, error)   { return b.Int64() }
+func uint64Of(b Literal) (interface{}, error)  { return b.Uint64() }
+func float64Of(b Literal) (interface{}, error) { return b.Float64() }
+func strOf(b Literal) (interface{}, error)     { return b.Str() }
+func bigIntOf(b Literal) (interface{}, error) {
+	n, err := b.BigInt()
+	if err != nil {
+		return nil, err
+	}
+	return n.String(), nil
+}
+func bigFloatOf(b Literal) (interface{}, error) {
+	f, err := b.BigFloat()
+	if err != nil {
+		return nil, err
+	}
+	return f.Text('g', 25), nil
+}
+
+func TestLiteralNumber(t *testing.T) {
+	if got := Literal("-1.5e10").Number(); got != "-1.5e10" {
+		t.Errorf("Number() = %q, want %q", got, "-1.5e10")
+	}
+	if got := Literal(`"1"`).Number(); got != "" {
+		t.Errorf("Number() = %q, want empty", got)
+	}
+	if _, err := Literal("1e400").Float64(); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("Float64 error = %v, want strconv.ErrRange", err)
+	}
+	if _, err := Literal("1.5").Int64(); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Int64 error = %v, want ErrNotInteger", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ValidationError reports an invalid node found by Value.Validate.
+type ValidationError struct {
+	// Pointer is the JSON pointer to the invalid value.
+	// For an invalid object member name, it is the pointer to the member.
+	Pointer string
+	// Err describes why the value is invalid.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("hujson: invalid value at %q: %v", e.Pointer, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate reports whether v is well-formed such that Pack produces
+// valid HuJSON that parses back into an equivalent value.
+// It verifies that every Extra contains only whitespace and comments
+// (where line comments must be terminated by a newline),
+// that every Literal is valid, that every object member name is
+// a valid JSON string or unquoted key, and that the StartOffset and EndOffset
+// of every value are consistent with the output of Pack.
+//
+// Offsets become stale when a value is edited,
+// in which case UpdateOffsets should be called before Validate.
+// The error is a *ValidationError for the first invalid value in document order.
+func (v *Value) Validate() error {
+	_, err := v.validate("", 0, false)
+	return err
+}
+
+// validate validates v, which begins at offset n, and returns the offset
+// after v. Name literals are validated separately by validateMemberName.
+func (v *Value) validate(ptr string, n int, isName bool) (int, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return &ValidationError{ptr, fmt.Errorf(format, args...)}
+	}
+	if !v.BeforeExtra.IsValid() {
+		return n, invalid("invalid whitespace or comments before value: %q", v.BeforeExtra)
+	}
+	n += len(v.BeforeExtra)
+	if v.StartOffset != n {
+		return n, invalid("StartOffset is %d, want %d", v.StartOffset, n)
+	}
+	var err error
+	switch v2 := v.Value.(type) {
+	case nil:
+		return n, invalid("missing value")
+	case Literal:
+		if !isName && !v2.IsValid() {
+			return n, invalid("invalid literal: %s", v2)
+		}
+		n += len(v2)
+	case *Object:
+		n += len("{")
+		for i := range v2.Members {
+			name, val := &v2.Members[i].Name, &v2.Members[i].Value
+			lit, ok := name.Value.(Literal)
+			if !ok {
+				return n, invalid("member %d has a non-literal name", i)
+			}
+			memberPtr := ptr + "/" + escapePointerToken(lit.memberName())
+			if err := validateMemberName(*name); err != nil {
+				return n, &ValidationError{memberPtr, err}
+			}
+			if n, err = name.validate(memberPtr, n, true); err != nil {
+				return n, err
+			}
+			n += len(":")
+			if n, err = val.validate(memberPtr, n, false); err != nil {
+				return n, err
+			}
+			n += len(",")
+		}
+		if v2.length() > 0 && !hasTrailingComma(v2) {
+			n -= len(",")
+		}
+		if !v2.AfterExtra.IsValid() {
+			return n, invalid("invalid whitespace or comments before closing brace: %q", v2.AfterExtra)
+		}
+		n += len(v2.AfterExtra)
+		n += len("}")
+	case *Array:
+		n += len("[")
+		for i := range v2.Elements {
+			if n, err = v2.Elements[i].validate(ptr+"/"+strconv.Itoa(i), n, false); err != nil {
+				return n, err
+			}
+			n += len(",")
+		}
+		if v2.length() > 0 && !hasTrailingComma(v2) {
+			n -= len(",")
+		}
+		if !v2.AfterExtra.IsValid() {
+			return n, invalid("invalid whitespace or comments before closing bracket: %q", v2.AfterExtra)
+		}
+		n += len(v2.AfterExtra)
+		n += len("]")
+	default:
+		return n, invalid("unknown value type %T", v2)
+	}
+	if v.EndOffset != n {
+		return n, invalid("EndOffset is %d, want %d", v.EndOffset, n)
+	}
+	if !v.AfterExtra.IsValid() {
+		return n, invalid("invalid whitespace or comments after value: %q", v.AfterExtra)
+	}
+	n += len(v.AfterExtra)
+	return n, nil
+}
+
+// validateMemberName reports whether name is a JSON string
+// or an unquoted key that parseKey would parse identically.
+func validateMemberName(name Value) error {
+	lit := name.Value.(Literal)
+	switch {
+	case lit.Kind() == '"':
+		if !lit.IsValid() {
+			return fmt.Errorf("invalid member name: %s", lit)
+		}
+		return nil
+	case len(lit) == 0 || !strings.ContainsRune("_abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", rune(lit[0])):
+		return fmt.Errorf("invalid member name: %s", lit)
+	case strings.ContainsAny(string(lit), ":\n\r\t "), !Literal(`"` + string(lit) + `"`).IsValid():
+		return fmt.Errorf("invalid unquoted member name: %s", lit)
+	case len(name.AfterExtra) > 0 && name.AfterExtra[0] == '/':
+		return fmt.Errorf("comment must be separated from unquoted member name %s by whitespace", lit)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hujson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	const in = `// Leading comment.
+{
+	"a": [1, /* two */ 2, 3,],
+	b: {"c": null}, // Trailing comment.
+	d_e: "f",
+}
+`
+	tests := []struct {
+		name        string
+		mutate      func(v *Value)
+		wantPointer string // empty if valid
+	}{
+		{name: "Parsed", mutate: func(v *Value) {}},
+		{name: "Built", mutate: func(v *Value) {
+			*v, _ = NewObject().Comment("c").Field("a", NewArray().Element(1)).Build()
+		}},
+		{name: "StaleOffsets", mutate: func(v *Value) {
+			v.Find("/a").Value.(*Array).Append(Value{Value: Int(4)})
+		}, wantPointer: "/a/3"},
+		{name: "UpdatedOffsets", mutate: func(v *Value) {
+			v.Find("/a").Value.(*Array).Append(Value{Value: Int(4)})
+			v.UpdateOffsets()
+		}},
+		{name: "InvalidLiteral", mutate: func(v *Value) {
+			v.Find("/a/1").Value = Literal("02")
+			v.UpdateOffsets()
+		}, wantPointer: "/a/1"},
+		{name: "UnterminatedLineComment", mutate: func(v *Value) {
+			v.Find("/a/2").BeforeExtra = Extra("// comment")
+			v.UpdateOffsets()
+		}, wantPointer: "/a/2"},
+		{name: "InvalidExtra", mutate: func(v *Value) {
+			v.Find("/b").Value.(*Object).AfterExtra = Extra("x")
+			v.UpdateOffsets()
+		}, wantPointer: "/b"},
+		{name: "InvalidUnquotedName", mutate: func(v *Value) {
+			v.Value.(*Object).Members[2].Name.Value = Literal("d e")
+			v.UpdateOffsets()
+		}, wantPointer: "/d e"},
+		{name: "CommentAfterUnquotedName", mutate: func(v *Value) {
+			v.Value.(*Object).Members[1].Name.AfterExtra = Extra("/**/")
+			v.UpdateOffsets()
+		}, wantPointer: "/b"},
+		{name: "MissingValue", mutate: func(v *Value) {
+			v.Find("/b/c").Value = nil
+		}, wantPointer: "/b/c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Parse([]byte(in))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			tt.mutate(&v)
+			err = v.Validate()
+			var verr *ValidationError
+			switch {
+			case tt.wantPointer == "" && err != nil:
+				t.Errorf("Validate error: %v", err)
+			case tt.wantPointer != "" && !errors.As(err, &verr):
+				t.Errorf("Validate error = %v, want *ValidationError", err)
+			case tt.wantPointer != "" && verr.Pointer != tt.wantPointer:
+				t.Errorf("Validate pointer = %q, want %q (error: %v)", verr.Pointer, tt.wantPointer, err)
+			}
+		})
+	}
+}